@@ -0,0 +1,40 @@
+// file: accents.go
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// accentFolds maps accented capitals to the plain letters placed on the grid.
+// Ligatures and ß expand to two cells, as is usual in French and German grids.
+var accentFolds = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Æ': "AE",
+	'Ç': "C",
+	'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E",
+	'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I",
+	'Ñ': "N",
+	'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O", 'Œ': "OE",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U",
+	'Ý': "Y", 'Ÿ': "Y",
+	'ß': "SS",
+}
+
+// foldWord returns word with accented letters replaced by their plain form
+// in folds, usually accentFolds or that of a language profile. Case is
+// preserved, so "Éclair" folds to "Eclair".
+func foldWord(word string, folds map[rune]string) string {
+	var b strings.Builder
+	for _, r := range word {
+		folded, ok := folds[unicode.ToUpper(r)]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.IsLower(r) {
+			folded = strings.ToLower(folded)
+		}
+		b.WriteString(folded)
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+// file: accessible.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The accessible export describes the puzzle in plain sentences, one entry
+// per line, for blind solvers using a screen reader or a braille display: no
+// grid drawing, just the size of the grid and every entry with its clue,
+// where it starts and which letters it shares with other entries. Answers
+// are not given.
+
+// entryLabel names e the way the description refers to it, e.g. "4 down" or
+// "7 across, running left".
+func entryLabel(p *Puzzle, e Entry) string {
+	label := fmt.Sprintf("%d down", e.Number)
+	if isAcross(e.Direction) {
+		label = fmt.Sprintf("%d across", e.Number)
+	}
+	if dir := otherDirection(p, e); dir != "" {
+		label += ", running " + dir
+	}
+	return label
+}
+
+// writeAccessible writes the linear description of p.
+func writeAccessible(w io.Writer, p *Puzzle) error {
+	m := p.Meta
+	if m.Title != "" {
+		fmt.Fprintf(w, "%s.\n", strings.TrimSuffix(m.Title, "."))
+	}
+	if m.Author != "" {
+		fmt.Fprintf(w, "By %s.\n", m.Author)
+	}
+	across, down := p.AcrossEntries(), p.DownEntries()
+	fmt.Fprintf(w, "Grid of %d rows by %d columns, with %d across and %d down entries.\n", p.Rows, p.Cols, len(across), len(down))
+	if m.Notes != "" {
+		fmt.Fprintf(w, "Notes: %s\n", m.Notes)
+	}
+
+	// which entries pass through every cell, and at which of their letters
+	type use struct {
+		e      Entry
+		letter int
+	}
+	cells := make(map[Pos][]use)
+	for _, e := range p.entries() {
+		for i, loc := range getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word) {
+			cells[loc] = append(cells[loc], use{e, i + 1})
+		}
+	}
+
+	for _, list := range []struct {
+		title   string
+		entries []Entry
+	}{{"Across", across}, {"Down", down}} {
+		fmt.Fprintf(w, "\n%s entries:\n", list.title)
+		for _, e := range list.entries {
+			n := len([]rune(e.Word))
+			line := fmt.Sprintf("%s, %d letters", entryLabel(p, e), n)
+			if enum := e.Enumeration(); enum != "" {
+				line += ", in words of " + enum
+			}
+			line += fmt.Sprintf(", starting at row %d, column %d.", e.Row+1, e.Col+1)
+			if e.Clue != "" {
+				line += " Clue: " + p.resolveClue(e.Clue)
+				if !strings.HasSuffix(line, ".") && !strings.HasSuffix(line, "?") && !strings.HasSuffix(line, "!") {
+					line += "."
+				}
+			} else {
+				line += " No clue."
+			}
+			var shared []string
+			for i, loc := range getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word) {
+				for _, u := range cells[loc] {
+					if u.e.Number == e.Number && u.e.Direction == e.Direction {
+						continue
+					}
+					shared = append(shared, fmt.Sprintf("letter %d with letter %d of %s", i+1, u.letter, entryLabel(p, u.e)))
+				}
+			}
+			if len(shared) > 0 {
+				line += " Shares " + strings.Join(shared, "; ") + "."
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	if m.Copyright != "" {
+		fmt.Fprintf(w, "\nCopyright %s\n", strings.TrimPrefix(m.Copyright, "© "))
+	}
+	return nil
+}
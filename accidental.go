@@ -0,0 +1,88 @@
+// file: accidental.go
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Crossings and, in word searches, the random fill can spell words nobody
+// chose. The scan reads every row and column of the finished grid left to
+// right and top to bottom and reports each dictionary word of at least
+// ACCIDENTAL_MIN_LEN letters that does not lie inside one of the answers.
+// Word searches are read the way solvers read them, in all eight
+// directions, so they are scanned backwards and diagonally too.
+
+// ACCIDENTAL_MIN_LEN is the shortest run the scan reports; shorter runs
+// spell too many words to be worth flagging.
+const ACCIDENTAL_MIN_LEN = 3
+
+// VIOLATION_ACCIDENTAL marks a dictionary word spelled by the grid by chance.
+const VIOLATION_ACCIDENTAL = "accidental"
+
+// findAccidental scans a rows x cols grid, where cell returns '#' for blocks,
+// reading in each of dirs, for words of dict that none of answers accounts
+// for.
+func findAccidental(rows, cols int, cell func(r, c int) rune, dirs []int, answers []Entry, dict *dictionary) []Violation {
+	covered := make([]map[Pos]bool, len(answers))
+	for i, e := range answers {
+		covered[i] = make(map[Pos]bool)
+		for _, loc := range getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word) {
+			covered[i][loc] = true
+		}
+	}
+	intended := func(cells []Pos) bool {
+		for _, set := range covered {
+			inside := true
+			for _, loc := range cells {
+				inside = inside && set[loc]
+			}
+			if inside {
+				return true
+			}
+		}
+		return false
+	}
+	inGrid := func(loc Pos) bool {
+		return loc.R >= 0 && loc.R < rows && loc.C >= 0 && loc.C < cols && cell(loc.R, loc.C) != '#'
+	}
+
+	var out []Violation
+	for _, dir := range dirs {
+		step := directionSteps[dir]
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				// every run starting at (r, c), up to a block or the edge
+				var cells []Pos
+				var letters []rune
+				for loc := (Pos{r, c}); inGrid(loc); loc = (Pos{loc.R + step.R, loc.C + step.C}) {
+					cells = append(cells, loc)
+					letters = append(letters, cell(loc.R, loc.C))
+					if len(cells) < ACCIDENTAL_MIN_LEN {
+						continue
+					}
+					if word := string(letters); dict.has(word) && !intended(cells) {
+						out = append(out, Violation{VIOLATION_ACCIDENTAL, slices.Clone(cells),
+							fmt.Sprintf("%s, reading %s", word, directionNames[dir])})
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Accidental returns the words of dict that p's grid spells outside its entries.
+func (p *Puzzle) Accidental(dict *dictionary) []Violation {
+	return findAccidental(p.Rows, p.Cols, p.Cell, []int{HORIZONTAL, VERTICAL}, p.entries(), dict)
+}
+
+// Accidental returns the words of dict that the fill of ws spells in any
+// direction, outside the hidden words.
+func (ws *WordSearch) Accidental(dict *dictionary) []Violation {
+	dirs := make([]int, len(directionSteps))
+	for dir := range dirs {
+		dirs[dir] = dir
+	}
+	return findAccidental(ws.Rows, ws.Cols, ws.Cell, dirs, ws.Entries(), dict)
+}
@@ -0,0 +1,26 @@
+// file: accidental_test.go
+package main
+
+import "testing"
+
+func TestWordSearchAccidentalEveryDirection(t *testing.T) {
+	// CAT is hidden across the top, so TAC reading back along it is no
+	// accident; the fill spells XOT leftwards and GOT up the diagonal
+	ws := &WordSearch{Rows: 3, Cols: 3, grid: map[Pos]rune{
+		{0, 0}: 'C', {0, 1}: 'A', {0, 2}: 'T',
+		{1, 0}: 'T', {1, 1}: 'O', {1, 2}: 'X',
+		{2, 0}: 'G', {2, 1}: 'X', {2, 2}: 'X',
+	}, entries: []Entry{{Row: 0, Col: 0, Direction: HORIZONTAL, Word: "CAT"}}}
+	got := make(map[string]bool)
+	for _, v := range ws.Accidental(newDictionary([]string{"CAT", "TAC", "GOT", "XOT"})) {
+		got[v.Message] = true
+	}
+	for _, want := range []string{"GOT, reading up-right", "XOT, reading left"} {
+		if !got[want] {
+			t.Errorf("missing %q in %v", want, got)
+		}
+	}
+	if len(got) != 2 {
+		t.Errorf("got %v, want only GOT and XOT", got)
+	}
+}
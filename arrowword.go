@@ -0,0 +1,145 @@
+// file: arrowword.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// An arrow word (Scandinavian crossword) has no separate clue list in print:
+// each clue sits in a blocked cell next to its entry, with an arrow showing
+// where the answer starts and which way it runs. Here the cells hold the
+// clue numbers and arrows, and the clues are listed below as usual.
+
+// ARROW_CLUES_PER_CELL is how many clues one blocked cell can hold.
+const ARROW_CLUES_PER_CELL = 2
+
+// arrowClue is one clue reference in a clue cell.
+type arrowClue struct {
+	number int
+	arrow  string
+}
+
+// arrowSpots lists where the clue of an entry may go, relative to its head,
+// best first, with the arrow drawn there.
+var arrowSpots = map[int][]struct {
+	offset Pos
+	arrow  string
+}{
+	HORIZONTAL: {{Pos{0, -1}, "→"}, {Pos{-1, 0}, "↳"}, {Pos{1, 0}, "↱"}},
+	VERTICAL:   {{Pos{-1, 0}, "↓"}, {Pos{0, -1}, "↴"}},
+}
+
+// arrowLayout picks a clue cell for every entry of p: a cell that holds no
+// letter next to the entry's head, preferring the one just before it. The
+// grid is extended by one row on top and one column on the left, where
+// entries along the edge get their clues, so the returned cells are in the
+// extended grid. Entries that found no free cell are returned as missing.
+func arrowLayout(p *Puzzle) (cells map[Pos][]arrowClue, missing []Entry) {
+	cells = make(map[Pos][]arrowClue)
+	for _, e := range p.entries() {
+		placed := false
+		for _, spot := range arrowSpots[e.Direction] {
+			loc := Pos{e.Row + spot.offset.R, e.Col + spot.offset.C}
+			if loc.R < -1 || loc.C < -1 || loc.R >= p.Rows || loc.C >= p.Cols || p.Cell(loc.R, loc.C) != '#' {
+				continue
+			}
+			cell := Pos{loc.R + 1, loc.C + 1}
+			if len(cells[cell]) < ARROW_CLUES_PER_CELL {
+				cells[cell] = append(cells[cell], arrowClue{e.Number, spot.arrow})
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			missing = append(missing, e)
+		}
+	}
+	for _, clues := range cells {
+		sort.SliceStable(clues, func(i, j int) bool { return clues[i].number < clues[j].number })
+	}
+	return cells, missing
+}
+
+// --- printArrowGrid
+// Draws p like printBoxGrid, but the blocked cells next to entries hold up to
+// two clue numbers with their arrows instead of a fill. The extra top row and
+// left column are only drawn when a clue needs them.
+func printArrowGrid(w io.Writer, p *Puzzle, blank, color bool) {
+	var colors map[Pos]string
+	if color && !blank {
+		colors = cellColors(p)
+	}
+	cells, missing := arrowLayout(p)
+	top, left := 1, 1 // extra row and column in use
+	for loc := range cells {
+		if loc.R == 0 {
+			top = 0
+		}
+		if loc.C == 0 {
+			left = 0
+		}
+	}
+	rows, cols := p.Rows+1-top, p.Cols+1-left
+
+	border := func(r int) string {
+		l, mid, rt := "├", "┼", "┤"
+		switch r {
+		case 0:
+			l, mid, rt = "┌", "┬", "┐"
+		case rows:
+			l, mid, rt = "└", "┴", "┘"
+		}
+		return l + strings.Repeat("───"+mid, cols-1) + "───" + rt
+	}
+	// clueLine returns line i of a clue cell, three columns wide
+	clueLine := func(clues []arrowClue, i int) string {
+		if i >= len(clues) {
+			return "   "
+		}
+		text := fmt.Sprintf("%d%s", clues[i].number, clues[i].arrow)
+		return text + strings.Repeat(" ", max(0, 3-len([]rune(text))))
+	}
+
+	for r := 0; r < rows; r++ {
+		fmt.Fprintln(w, border(r))
+		upper, lower := "│", "│"
+		for c := 0; c < cols; c++ {
+			cell := Pos{r + top, c + left} // in the extended grid
+			ch := p.Cell(cell.R-1, cell.C-1)
+			if ch != '#' {
+				open, shut := markSides(p, Pos{cell.R - 1, cell.C - 1})
+				letter := open + string(ch) + shut
+				if blank {
+					letter = open + " " + shut
+				} else if col, ok := colors[Pos{cell.R - 1, cell.C - 1}]; ok {
+					letter = col + letter + ansiReset
+				}
+				upper += "   │"
+				lower += letter + "│"
+				continue
+			}
+			clues, ok := cells[cell]
+			if !ok {
+				fill := "███"
+				if color {
+					fill = ansiDim + fill + ansiReset
+				}
+				upper += fill + "│"
+				lower += fill + "│"
+				continue
+			}
+			upper += clueLine(clues, 0) + "│"
+			lower += clueLine(clues, 1) + "│"
+		}
+		fmt.Fprintln(w, upper)
+		fmt.Fprintln(w, lower)
+	}
+	fmt.Fprintln(w, border(rows))
+
+	for _, e := range missing {
+		fmt.Fprintf(w, "(no clue cell for %d %s)\n", e.Number, directionNames[e.Direction])
+	}
+}
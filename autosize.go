@@ -0,0 +1,62 @@
+// file: autosize.go
+package main
+
+import (
+	"io"
+	"log/slog"
+)
+
+// With automatic sizing the grid size is an output instead of an input: the
+// search starts on a grid as wide as the longest word and grows it one row and
+// column at a time until a layout meets every requirement. The sizes are
+// tried in turn rather than bisected: the search is random, so success is not
+// monotonic in the size and a bisection could skip a smaller grid that works.
+
+// AUTO_SIZE_MAX is the largest grid tried; beyond it generate would switch
+// to tiling, which does not aim for the intersection requirement.
+const AUTO_SIZE_MAX = GIANT_GRID_SIZE - 1
+
+// generateAutoSize returns the puzzle from the smallest grid, up to maxSize,
+// on which generate met opts' requirements. opts.GridSize is ignored. If no
+// size worked, the best puzzle from maxSize is returned. opts.Progress is
+// not used, since every size runs its own iteration loop.
+func generateAutoSize(words []string, maxSize int, opts Options) *Puzzle {
+	opts.Progress = nil
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	size := 1
+	for _, w := range words {
+		placed := gridForm(w, opts.folds())
+		if l := len([]rune(placed)); l > size {
+			size = l
+		}
+	}
+
+	var best *Puzzle
+	for ; size <= maxSize; size++ {
+		opts.GridSize = size
+		best = generate(words, opts)
+		if best != nil && opts.met(best) {
+			logger.Debug("grid size found", "size", size, "intersections", best.Intersections())
+			return best
+		}
+		logger.Debug("grid size too small", "size", size)
+	}
+	return best
+}
+
+// met reports whether p satisfies every requirement in opts.
+func (opts Options) met(p *Puzzle) bool {
+	if p.Intersections() < opts.ReqIntersections || !opts.dense(p) || !opts.connected(p) {
+		return false
+	}
+	for _, e := range p.entries() {
+		if p.Crossings(e) < opts.MinCrossings {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+// file: bars.go
+package main
+
+import "strings"
+
+// Barred grids, common in British cryptics, have few or no blocks: entries
+// end at thick bars drawn between cells instead. A bar is stored on the cell
+// before it, as a bitmask of the sides it runs along.
+const (
+	BAR_RIGHT = 1 << iota // between the cell and the one to its right
+	BAR_BELOW             // between the cell and the one below it
+)
+
+// barSide returns the bar that ends a run in direction after a cell.
+func barSide(direction int) int {
+	if direction == VERTICAL {
+		return BAR_BELOW
+	}
+	return BAR_RIGHT
+}
+
+// SetBar puts a bar on side (BAR_RIGHT or BAR_BELOW) of loc. The entries are
+// not renumbered; build barred puzzles with puzzleFromBarredGrid.
+func (p *Puzzle) SetBar(loc Pos, side int) {
+	if p.bars == nil {
+		p.bars = make(map[Pos]int)
+	}
+	p.bars[loc] |= side
+}
+
+// Bar reports whether loc has a bar on side.
+func (p *Puzzle) Bar(loc Pos, side int) bool {
+	return p.bars[loc]&side != 0
+}
+
+// Barred reports whether p has any bars.
+func (p *Puzzle) Barred() bool {
+	return len(p.bars) > 0
+}
+
+// puzzleFromBarredGrid is puzzleFromGrid for a grid with bars: runs of
+// letters end at blocks and at bars, and are numbered accordingly.
+func puzzleFromBarredGrid(rows, cols int, grid map[Pos]rune, bars map[Pos]int) *Puzzle {
+	p := &Puzzle{Rows: rows, Cols: cols, grid: make(map[Pos]rune)}
+	for loc, ch := range grid {
+		if ch != '#' {
+			p.grid[loc] = ch
+		}
+	}
+	for loc, sides := range bars {
+		if sides != 0 {
+			p.SetBar(loc, sides)
+		}
+	}
+	p.numberRuns()
+	return p
+}
+
+// runs returns the runs of two or more letters of p, split by blocks and bars.
+func (p *Puzzle) runs() []slot {
+	isBlock := func(loc Pos) bool { return p.Cell(loc.R, loc.C) == '#' }
+	return findBarredSlots(p.Rows, p.Cols, isBlock, p.Bar, 2)
+}
+
+// barRows returns the bars of p one string per row, like the grid: '|' for
+// a bar to the right of a cell, '_' for one below it, '+' for both and ' '
+// for none.
+func (p *Puzzle) barRows() []string {
+	rows := make([]string, p.Rows)
+	for r := range rows {
+		var b strings.Builder
+		for c := 0; c < p.Cols; c++ {
+			b.WriteByte(" |_+"[p.bars[Pos{r, c}]])
+		}
+		rows[r] = b.String()
+	}
+	return rows
+}
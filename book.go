@@ -0,0 +1,330 @@
+// file: book.go
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The book subcommand builds a printable puzzle booklet: one puzzle per page
+// with its clues, and the solutions at the back, in a single PDF. With an
+// -out name ending in .epub it makes an EPUB puzzle pack for e-readers
+// instead, each puzzle page linking to its solution.
+//
+//	crossword book -wordfile week1.txt,week2.txt,week3.txt -clues clues.txt -out week.pdf
+//	crossword book -wordfile pool.txt -count 20 -per-puzzle 12 -out pack.epub
+//
+// Several word files give one puzzle each. A single file with -count N gives
+// N puzzles, each from -per-puzzle words drawn from it (all of them if 0).
+// Every other generation flag applies to each puzzle; -word-bank lists the
+// answers under each grid for fill-in puzzles, -profile large-print makes
+// the book for low-vision solvers, -theme changes its look and -qr-url
+// links every puzzle page to its online version.
+
+// Page layout of the book, in points.
+const (
+	BOOK_MARGIN  = 48
+	BOOK_QR_SIZE = 64 // QR code in the top-right corner of a puzzle page
+)
+
+// bookProfile is the look of a book, chosen with -profile. Sizes are in
+// points.
+type bookProfile struct {
+	theme      string  // built-in theme used unless -theme is given
+	gridHeight float64 // most of the page a grid may take
+	clueSize   float64
+	leading    float64 // distance between clue lines
+	clueFont   string
+	columns    int // clue columns per page
+	solutions  Pos // solution grids per solutions page, as rows by columns
+}
+
+// bookProfiles are the -profile choices. large-print is for low-vision
+// solvers: big cells with heavy lines, and large bold clues in one column.
+var bookProfiles = map[string]bookProfile{
+	"standard":    {theme: "classic", gridHeight: 380, clueSize: 9, leading: 11, clueFont: PDF_FONT, columns: 2, solutions: Pos{3, 2}},
+	"large-print": {theme: "high-contrast", gridHeight: 600, clueSize: 16, leading: 21, clueFont: PDF_FONT_BOLD, columns: 1, solutions: Pos{2, 1}},
+}
+
+// profileNames lists the book profiles for help and error messages.
+func profileNames() []string {
+	names := make([]string, 0, len(bookProfiles))
+	for name := range bookProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runBook is main for the book subcommand. Returns the process exit code.
+func runBook(c *cli, logger *slog.Logger) int {
+	pools, err := c.wordPools()
+	if err != nil {
+		logger.Error("cannot read word list", "err", err)
+		return EXIT_USAGE
+	}
+	clues, err := c.clues()
+	if err != nil {
+		logger.Error("cannot read clues", "err", err)
+		return EXIT_USAGE
+	}
+	block, err := c.blocked()
+	if err != nil {
+		logger.Error("cannot read the -blocklist", "err", err)
+		return EXIT_USAGE
+	}
+	if pools, err = screen(pools, block, logger); err != nil {
+		logger.Error("no words left", "err", err)
+		return EXIT_USAGE
+	}
+	if pools, err = c.prune(pools, logger); err != nil {
+		logger.Error("no words left", "err", err)
+		return EXIT_USAGE
+	}
+	if err := checkRestart(c.restart); err != nil {
+		fmt.Fprintf(os.Stderr, "%v (want %s)\n", err, strings.Join(restartNames(), ", "))
+		return EXIT_USAGE
+	}
+	if _, err := c.placementDirections(); err != nil {
+		fmt.Fprintf(os.Stderr, "-directions: %v\n", err)
+		return EXIT_USAGE
+	}
+	profile, ok := bookProfiles[c.profile]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown -profile %q (want %s)\n", c.profile, strings.Join(profileNames(), " or "))
+		return EXIT_USAGE
+	}
+	themeName := c.theme
+	if themeName == "" {
+		themeName = profile.theme
+	}
+	theme, err := loadTheme(themeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-theme: %v\n", err)
+		return EXIT_USAGE
+	}
+	if c.cellSize > 0 {
+		theme.CellSize = c.cellSize
+	}
+	lists := bookWordLists(pools, c.count, c.perPuzzle)
+
+	code := EXIT_OK
+	var puzzles []*Puzzle
+	for i, words := range lists {
+		opts := c.options(logger)
+		var p *Puzzle
+		if c.autoSize {
+			p = generateAutoSize(words, AUTO_SIZE_MAX, opts)
+		} else {
+			p = generate(words, opts)
+		}
+		if opts.Stats != nil {
+			writeStats(os.Stderr, opts.Stats)
+		}
+		if opts.Trace != nil {
+			writeTrace(os.Stderr, opts.Trace, p)
+		}
+		if p == nil || len(p.entries()) == 0 {
+			logger.Error("no valid puzzle produced", "puzzle", i+1)
+			return EXIT_NO_PUZZLE
+		}
+		if p.Intersections() < c.reqIntersections {
+			logger.Warn("intersection requirement not met", "puzzle", i+1, "intersections", p.Intersections(), "required", c.reqIntersections)
+			code = EXIT_BELOW
+		}
+		if block != nil {
+			for _, v := range p.Accidental(block) {
+				logger.Warn("grid spells a blocked word", "puzzle", i+1, "problem", v)
+			}
+		}
+		p = p.Crop(0)
+		if c.rtl {
+			p = p.RightToLeft()
+		}
+		p.Meta = c.metadata(p.Meta)
+		if clues != nil {
+			if err := p.setClues(clues); err != nil {
+				logger.Warn("clue lookup failed", "puzzle", i+1, "err", err)
+			}
+		}
+		for _, ref := range p.brokenRefs() {
+			logger.Warn("clue refers to an answer not in the puzzle", "puzzle", i+1, "answer", ref)
+		}
+		puzzles = append(puzzles, p)
+		logger.Info("puzzle generated", "puzzle", i+1, "words", len(p.entries()), "intersections", p.Intersections())
+	}
+
+	name := c.out
+	if name == "" {
+		name = "book"
+	}
+	write := func(w io.Writer) error { return writeBook(w, puzzles, profile, theme, c.wordBank, c.qrURL) }
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".epub":
+		title := c.meta.Title
+		if title == "" {
+			title = "Puzzles"
+		}
+		write = func(w io.Writer) error { return writeEPUB(w, puzzles, title) }
+	case ".pdf":
+	default:
+		name += ".pdf"
+	}
+	if err := writeFile(name, write); err != nil {
+		logger.Error("cannot write book", "err", err)
+		return EXIT_FAILURE
+	}
+	logger.Info("book written", "file", name, "puzzles", len(puzzles))
+	return code
+}
+
+// bookWordLists returns the word list of every puzzle of the book: one per
+// pool if there are several, otherwise count lists of per words drawn from
+// the single pool without repeats until it runs out (all of it if per is 0).
+func bookWordLists(pools [][]string, count, per int) [][]string {
+	if len(pools) > 1 {
+		return pools
+	}
+	pool := pools[0]
+	if per <= 0 || per >= len(pool) {
+		lists := make([][]string, count)
+		for i := range lists {
+			lists[i] = pool
+		}
+		return lists
+	}
+	var lists [][]string
+	var deck []string
+	for len(lists) < count {
+		if len(deck) < per {
+			deck = append([]string(nil), pool...)
+			rand.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+		}
+		lists = append(lists, deck[:per])
+		deck = deck[per:]
+	}
+	return lists
+}
+
+// writeBook lays the puzzles out one per page as profile and theme say, with
+// the word bank under the grid if wordBank is set and clues flowing into
+// further columns and pages as needed, and the solutions at the back. If
+// qrURL is set, every puzzle page gets a QR code of it in the top-right
+// corner, see puzzleURL.
+func writeBook(w io.Writer, puzzles []*Puzzle, profile bookProfile, theme Theme, wordBank bool, qrURL string) error {
+	doc := &pdfDoc{font: theme.Font, cjkFont: theme.CJKFont, ink: theme.Ink}
+	for i, p := range puzzles {
+		page := doc.addPage()
+		if qrURL != "" {
+			code, err := encodeQR(puzzleURL(qrURL, p, i))
+			if err != nil {
+				return err
+			}
+			page.qr(PDF_PAGE_WIDTH-BOOK_QR_SIZE-8, PDF_PAGE_HEIGHT-8, BOOK_QR_SIZE, code)
+		}
+		title := fmt.Sprintf("Puzzle %d", i+1)
+		if p.Meta.Title != "" {
+			title = fmt.Sprintf("%s %d", p.Meta.Title, i+1)
+		}
+		top := float64(PDF_PAGE_HEIGHT - BOOK_MARGIN)
+		page.centredText(PDF_PAGE_WIDTH/2, top-16, 16, PDF_FONT_BOLD, title)
+		top -= 24
+		if p.Meta.Author != "" {
+			page.centredText(PDF_PAGE_WIDTH/2, top-10, 10, PDF_FONT, "by "+p.Meta.Author)
+			top -= 14
+		}
+		top -= 12
+
+		width := float64(PDF_PAGE_WIDTH - 2*BOOK_MARGIN)
+		cell := min(width/float64(p.Cols), profile.gridHeight/float64(p.Rows), theme.CellSize)
+		pdfGrid(page, p, (PDF_PAGE_WIDTH-cell*float64(p.Cols))/2, top, cell, theme, true)
+		top -= cell*float64(p.Rows) + 24
+		if wordBank {
+			page.text(BOOK_MARGIN, top, profile.clueSize, PDF_FONT_BOLD, "Word bank")
+			top -= profile.leading
+			for _, line := range pdfWrap(strings.Join(p.WordBank(), ", "), profile.clueSize, width) {
+				page.text(BOOK_MARGIN, top, profile.clueSize, profile.clueFont, line)
+				top -= profile.leading
+			}
+			top -= profile.leading
+		}
+
+		writeBookClues(doc, page, p, profile, top)
+	}
+
+	// solutions, several to a page
+	across, down := profile.solutions.C, profile.solutions.R
+	perPage := across * down
+	slotW := float64(PDF_PAGE_WIDTH-2*BOOK_MARGIN) / float64(across)
+	slotH := float64(PDF_PAGE_HEIGHT-2*BOOK_MARGIN-30) / float64(down)
+	var page *pdfPage
+	for i, p := range puzzles {
+		if i%perPage == 0 {
+			page = doc.addPage()
+			page.centredText(PDF_PAGE_WIDTH/2, PDF_PAGE_HEIGHT-BOOK_MARGIN-16, 16, PDF_FONT_BOLD, "Solutions")
+		}
+		col, row := i%perPage%across, i%perPage/across
+		x := BOOK_MARGIN + float64(col)*slotW
+		y := PDF_PAGE_HEIGHT - BOOK_MARGIN - 30 - float64(row)*slotH
+		page.text(x, y-12, 10, PDF_FONT_BOLD, fmt.Sprintf("Puzzle %d", i+1))
+		cell := min((slotW-12)/float64(p.Cols), (slotH-30)/float64(p.Rows), theme.CellSize)
+		pdfGrid(page, p, x, y-18, cell, theme, false)
+	}
+	return doc.write(w)
+}
+
+// puzzleURL fills in the -qr-url template for the i-th puzzle (0-based) of a
+// book: {id} becomes the puzzle's ID, the start of its CanonicalHash, and
+// {n} its number in the book.
+func puzzleURL(template string, p *Puzzle, i int) string {
+	return strings.NewReplacer("{id}", p.CanonicalHash()[:12], "{n}", fmt.Sprint(i+1)).Replace(template)
+}
+
+// writeBookClues writes the clues of p from top down on page in the columns
+// of profile, adding pages when they run out.
+func writeBookClues(doc *pdfDoc, page *pdfPage, p *Puzzle, profile bookProfile, top float64) {
+	colW := (float64(PDF_PAGE_WIDTH-2*BOOK_MARGIN) - 24*float64(profile.columns-1)) / float64(profile.columns)
+	indent := pdfTextWidth("000", profile.clueSize) + 4 // room for the clue number
+	x, y := float64(BOOK_MARGIN), top
+	col := 0
+	emit := func(text, font string, indent float64) {
+		if y < BOOK_MARGIN {
+			col++
+			if col == profile.columns {
+				page, col, top = doc.addPage(), 0, PDF_PAGE_HEIGHT-BOOK_MARGIN
+			}
+			x, y = BOOK_MARGIN+float64(col)*(colW+24), top
+		}
+		page.text(x+indent, y, profile.clueSize, font, text)
+		y -= profile.leading
+	}
+	for _, list := range []struct {
+		title   string
+		entries []Entry
+	}{{"Across", p.AcrossEntries()}, {"Down", p.DownEntries()}} {
+		emit(list.title, PDF_FONT_BOLD, 0)
+		for _, e := range list.entries {
+			clue := p.ClueText(e)
+			if e.Clue == "" {
+				clue = "(" + e.lengths() + ")"
+			}
+			number := fmt.Sprintf("%d", e.Number)
+			for j, line := range pdfWrap(clue, profile.clueSize, colW-indent) {
+				if j == 0 {
+					emit(number, PDF_FONT_BOLD, 0)
+					y += profile.leading // the clue goes on the number's line
+				}
+				emit(line, profile.clueFont, indent)
+			}
+		}
+		y -= profile.leading / 2
+	}
+}
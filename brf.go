@@ -0,0 +1,149 @@
+// file: brf.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// BRF is the file format braille embossers and refreshable displays read:
+// North American ASCII braille, where every printable character stands for
+// one six-dot cell, laid out in pages of 25 lines of 40 cells. The export is
+// uncontracted (grade 1) braille without capital signs, which transcribers
+// can take as is or run through their own translation: the clue list, then
+// an answer key giving every answer with the cell it starts in.
+
+// BRF page size, in braille cells.
+const (
+	BRF_LINE_CELLS = 40
+	BRF_PAGE_LINES = 25
+)
+
+// Braille signs in ASCII braille.
+const (
+	brfNumberSign = '#' // dots 3-4-5-6: the following letters a-j are digits
+	brfLetterSign = ';' // dots 5-6: the following letter is a letter after all
+)
+
+// brfPunctuation maps print punctuation to its grade 1 braille cell.
+var brfPunctuation = map[rune]string{
+	',': "1", ';': "2", ':': "3", '.': "4", '!': "6", '(': "7", ')': "7",
+	'?': "8", '"': "8", '\'': "'", '’': "'", '-': "-", '/': "/", ' ': " ",
+}
+
+// brfText transcribes s into uncontracted ASCII braille. Accented letters are
+// folded; characters braille has no cell for here are dropped.
+func brfText(s string) string {
+	var b strings.Builder
+	number := false // inside a number, after its number sign
+	for _, r := range foldWord(s, accentFolds) {
+		r = unicode.ToLower(r)
+		switch {
+		case r >= '0' && r <= '9':
+			if !number {
+				b.WriteByte(brfNumberSign)
+				number = true
+			}
+			// 1-9 are the letters a-i, 0 is j
+			b.WriteByte("JABCDEFGHI"[r-'0'])
+			continue
+		case r >= 'a' && r <= 'z':
+			if number && r <= 'j' {
+				b.WriteByte(brfLetterSign)
+			}
+			b.WriteRune(unicode.ToUpper(r))
+		default:
+			cell, ok := brfPunctuation[r]
+			if !ok {
+				continue
+			}
+			b.WriteString(cell)
+		}
+		number = false
+	}
+	return b.String()
+}
+
+// brfWriter lays braille text out in lines and pages.
+type brfWriter struct {
+	w     io.Writer
+	lines int // on the current page
+}
+
+// line writes text wrapped to the line length, continuation lines indented
+// by two cells, starting a new page when one fills up.
+func (bw *brfWriter) line(text string) {
+	indent := ""
+	for {
+		width := BRF_LINE_CELLS - len(indent)
+		cut := len(text)
+		if cut > width {
+			cut = strings.LastIndexByte(text[:width+1], ' ')
+			if cut <= 0 {
+				cut = width
+			}
+		}
+		bw.emit(indent + strings.TrimRight(text[:cut], " "))
+		text = strings.TrimLeft(text[cut:], " ")
+		if text == "" {
+			return
+		}
+		indent = "  "
+	}
+}
+
+// emit writes one line.
+func (bw *brfWriter) emit(line string) {
+	if bw.lines == BRF_PAGE_LINES {
+		fmt.Fprint(bw.w, "\f")
+		bw.lines = 0
+	}
+	fmt.Fprint(bw.w, line+"\r\n")
+	bw.lines++
+}
+
+// writeBRF writes the clues and answer key of p in BRF.
+func writeBRF(w io.Writer, p *Puzzle) error {
+	bw := &brfWriter{w: w}
+	if p.Meta.Title != "" {
+		bw.line(brfText(p.Meta.Title))
+	}
+	if p.Meta.Author != "" {
+		bw.line(brfText("by " + p.Meta.Author))
+	}
+	bw.line(brfText(fmt.Sprintf("grid %d rows by %d columns", p.Rows, p.Cols)))
+	if p.Meta.Notes != "" {
+		bw.line(brfText(p.Meta.Notes))
+	}
+
+	lists := []struct {
+		title   string
+		entries []Entry
+	}{{"across", p.AcrossEntries()}, {"down", p.DownEntries()}}
+	for _, list := range lists {
+		bw.emit("")
+		bw.line(brfText(list.title))
+		for _, e := range list.entries {
+			clue := p.resolveClue(e.Clue)
+			if dir := otherDirection(p, e); dir != "" {
+				clue = strings.TrimSpace("(" + dir + ") " + clue)
+			}
+			bw.line(brfText(strings.Join(strings.Fields(fmt.Sprintf("%d %s (%s)", e.Number, clue, e.lengths())), " ")))
+		}
+	}
+
+	bw.emit("")
+	bw.line(brfText("answers"))
+	for _, list := range lists {
+		for _, e := range list.entries {
+			bw.line(brfText(fmt.Sprintf("%s, row %d column %d: %s", entryLabel(p, e), e.Row+1, e.Col+1, e.Display)))
+		}
+	}
+	if p.Meta.Copyright != "" {
+		bw.emit("")
+		bw.line(brfText("copyright " + strings.TrimPrefix(p.Meta.Copyright, "© ")))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// file: builtin.go
+package main
+
+import (
+	"embed"
+	"strings"
+)
+
+// The binary carries public-domain word lists, so the solve subcommand, the
+// -accidental scan and mini fills work without a word file at hand. Give
+// BUILTIN_WORDLIST wherever a word list path is asked for, or
+// BUILTIN_WORDLIST:lang for the list of another language profile:
+//
+//	crossword solve -grid partial.txt -dict builtin
+//	crossword -accidental builtin:de
+
+// BUILTIN_WORDLIST is the word list path naming the embedded list of
+// DEFAULT_LANGUAGE.
+const BUILTIN_WORDLIST = "builtin"
+
+//go:embed wordlists/*.txt
+var builtinLists embed.FS
+
+// builtinWordList returns the embedded list path names, if it names one.
+func builtinWordList(path string) ([]byte, bool, error) {
+	name, ok := strings.CutPrefix(path, BUILTIN_WORDLIST)
+	if !ok || name != "" && !strings.HasPrefix(name, ":") {
+		return nil, false, nil
+	}
+	lang, err := lookupLanguage(strings.TrimPrefix(name, ":"))
+	if err != nil {
+		return nil, true, err
+	}
+	data, err := builtinLists.ReadFile("wordlists/" + lang.list)
+	return data, true, err
+}
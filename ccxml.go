@@ -0,0 +1,157 @@
+// file: ccxml.go
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Crossword Compiler's XML interchange format (the .xml a .ccw exports to
+// and the applet reads). Coordinates are 1-based, x is the column.
+
+type ccCompiler struct {
+	XMLName xml.Name    `xml:"crossword-compiler"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Puzzle  ccRectangle `xml:"rectangular-puzzle"`
+}
+
+type ccRectangle struct {
+	XMLNS     string      `xml:"xmlns,attr"`
+	Alphabet  string      `xml:"alphabet,attr"`
+	Metadata  ccMetadata  `xml:"metadata"`
+	Crossword ccCrossword `xml:"crossword"`
+}
+
+type ccMetadata struct {
+	Title       string `xml:"title"`
+	Creator     string `xml:"creator"`
+	Copyright   string `xml:"copyright"`
+	Description string `xml:"description"`
+}
+
+type ccCrossword struct {
+	Grid  ccGrid    `xml:"grid"`
+	Words []ccWord  `xml:"word"`
+	Clues []ccClues `xml:"clues"`
+}
+
+type ccGrid struct {
+	Width  int      `xml:"width,attr"`
+	Height int      `xml:"height,attr"`
+	Look   ccLook   `xml:"grid-look"`
+	Cells  []ccCell `xml:"cell"`
+}
+
+type ccLook struct {
+	NumberingScheme string `xml:"numbering-scheme,attr"`
+}
+
+type ccCell struct {
+	X        int    `xml:"x,attr"`
+	Y        int    `xml:"y,attr"`
+	Type     string `xml:"type,attr,omitempty"`
+	Solution string `xml:"solution,attr,omitempty"`
+	Number   string `xml:"number,attr,omitempty"`
+	RightBar bool   `xml:"right-bar,attr,omitempty"`
+	BelowBar bool   `xml:"bottom-bar,attr,omitempty"`
+	Shape    string `xml:"background-shape,attr,omitempty"`
+	Colour   string `xml:"background-color,attr,omitempty"`
+}
+
+type ccWord struct {
+	ID int    `xml:"id,attr"`
+	X  string `xml:"x,attr"`
+	Y  string `xml:"y,attr"`
+}
+
+type ccClues struct {
+	Ordering string   `xml:"ordering,attr"`
+	Title    ccTitle  `xml:"title"`
+	Clues    []ccClue `xml:"clue"`
+}
+
+type ccTitle struct {
+	B string `xml:"b"`
+}
+
+type ccClue struct {
+	Word   int    `xml:"word,attr"`
+	Number string `xml:"number,attr"`
+	Format string `xml:"format,attr"`
+	Text   string `xml:",chardata"`
+}
+
+// writeCCXML writes p in Crossword Compiler XML, with the grid, its bars and
+// marked cells, the numbering, one word element per entry and the clues.
+func writeCCXML(w io.Writer, p *Puzzle) error {
+	if err := checkAcrossDown(p, "ccxml"); err != nil {
+		return err
+	}
+	doc := ccCompiler{
+		XMLNS: "http://crossword.info/xml/crossword-compiler",
+		Puzzle: ccRectangle{
+			XMLNS:    "http://crossword.info/xml/rectangular-puzzle",
+			Alphabet: "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		},
+	}
+	doc.Puzzle.Metadata = ccMetadata{Title: p.Meta.Title, Creator: p.Meta.Author, Copyright: p.Meta.Copyright, Description: p.Meta.Notes}
+	cw := &doc.Puzzle.Crossword
+	cw.Grid = ccGrid{Width: p.Cols, Height: p.Rows, Look: ccLook{NumberingScheme: "normal"}}
+
+	numbers := make(map[Pos]int)
+	for _, e := range p.entries() {
+		numbers[Pos{e.Row, e.Col}] = e.Number
+	}
+	for r := 0; r < p.Rows; r++ {
+		for c := 0; c < p.Cols; c++ {
+			cell := ccCell{X: c + 1, Y: r + 1}
+			if ch := p.Cell(r, c); ch == '#' {
+				cell.Type = "block"
+			} else {
+				cell.Solution = string(ch)
+			}
+			if n, ok := numbers[Pos{r, c}]; ok {
+				cell.Number = fmt.Sprint(n)
+			}
+			cell.RightBar = p.Bar(Pos{r, c}, BAR_RIGHT)
+			cell.BelowBar = p.Bar(Pos{r, c}, BAR_BELOW)
+			if p.Marked(Pos{r, c}, MARK_CIRCLE) {
+				cell.Shape = "circle"
+			}
+			if p.Marked(Pos{r, c}, MARK_SHADE) {
+				cell.Colour = SHADE_COLOUR
+			}
+			cw.Grid.Cells = append(cw.Grid.Cells, cell)
+		}
+	}
+
+	for _, list := range []struct {
+		title   string
+		entries []Entry
+	}{{"Across", p.AcrossEntries()}, {"Down", p.DownEntries()}} {
+		clues := ccClues{Ordering: "normal", Title: ccTitle{list.title}}
+		for _, e := range list.entries {
+			id := len(cw.Words) + 1
+			n := len([]rune(e.Word))
+			word := ccWord{ID: id, X: fmt.Sprint(e.Col + 1), Y: fmt.Sprintf("%d-%d", e.Row+1, e.Row+n)}
+			if e.Direction == HORIZONTAL {
+				word.X, word.Y = fmt.Sprintf("%d-%d", e.Col+1, e.Col+n), fmt.Sprint(e.Row+1)
+			}
+			cw.Words = append(cw.Words, word)
+			clues.Clues = append(clues.Clues, ccClue{Word: id, Number: fmt.Sprint(e.Number), Format: e.lengths(), Text: p.resolveClue(e.Clue)})
+		}
+		cw.Clues = append(cw.Clues, clues)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
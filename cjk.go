@@ -0,0 +1,80 @@
+// file: cjk.go
+package main
+
+// Chinese, Japanese and Korean puzzles hold a whole character (a syllable
+// or kana) per cell. Matching and crossing already work on runes, so only
+// the drawing needs care: terminals show these characters two columns
+// wide, which the text grids make up for by widening every cell of a grid
+// holding one, and the PDF draws them in one of the Asian fonts PDF readers
+// provide (see Theme.CJKFont).
+
+// runeWidth returns the number of terminal columns r takes: 2 for the East
+// Asian wide and fullwidth characters, 1 otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115F, // Hangul initial consonants
+		r >= 0x2E80 && r <= 0xA4CF && r != 0x303F, // CJK radicals to Yi
+		r >= 0xAC00 && r <= 0xD7A3,                // Hangul syllables
+		r >= 0xF900 && r <= 0xFAFF,                // CJK compatibility ideographs
+		r >= 0xFE30 && r <= 0xFE4F,                // CJK compatibility forms
+		r >= 0xFF00 && r <= 0xFF60,                // fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x20000 && r <= 0x3FFFD: // CJK extension planes
+		return 2
+	}
+	return 1
+}
+
+// hasWide reports whether s holds a wide character.
+func hasWide(s string) bool {
+	for _, r := range s {
+		if runeWidth(r) == 2 {
+			return true
+		}
+	}
+	return false
+}
+
+// wide reports whether the grid of p holds a wide character, so its cells
+// are drawn two columns wide.
+func (p *Puzzle) wide() bool {
+	for _, ch := range p.grid {
+		if runeWidth(ch) == 2 {
+			return true
+		}
+	}
+	return false
+}
+
+// padCell returns cell, showing ch, padded with spaces to width columns.
+func padCell(cell string, ch rune, width int) string {
+	for n := runeWidth(ch); n < width; n++ {
+		cell += " "
+	}
+	return cell
+}
+
+// cjkPieces splits a word of text into the pieces a line may break between:
+// every wide character on its own, and runs of other characters together.
+// Pieces after the first join without a space.
+func cjkPieces(word string) []string {
+	if !hasWide(word) {
+		return []string{word}
+	}
+	var pieces []string
+	run := ""
+	for _, r := range word {
+		if runeWidth(r) == 1 {
+			run += string(r)
+			continue
+		}
+		if run != "" {
+			pieces, run = append(pieces, run), ""
+		}
+		pieces = append(pieces, string(r))
+	}
+	if run != "" {
+		pieces = append(pieces, run)
+	}
+	return pieces
+}
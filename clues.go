@@ -0,0 +1,146 @@
+// file: clues.go
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ClueProvider writes a clue for an answer. Implementations return
+// errNoClue when they have nothing for the word.
+type ClueProvider interface {
+	Clue(word string) (string, error)
+}
+
+var errNoClue = errors.New("no clue for word")
+
+// setClues asks provider for a clue for every entry. Words without a clue
+// keep an empty one; the first other error stops the lookups.
+func (p *Puzzle) setClues(provider ClueProvider) error {
+	for _, list := range [][]Entry{p.across, p.down} {
+		for i := range list {
+			clue, err := provider.Clue(list[i].Display)
+			if errors.Is(err, errNoClue) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("clue for %s: %w", list[i].Display, err)
+			}
+			list[i].Clue = clue
+		}
+	}
+	return nil
+}
+
+// dictClues looks clues up in definition files loaded into memory.
+type dictClues map[string]string
+
+// readClueFiles loads clue files. Two formats are understood, line by line:
+// WordNet database files (data.noun, data.verb, ...), where the definition
+// part of the gloss becomes the clue, and plain "WORD<tab>clue" lines. Blank
+// lines and '#' comments are skipped. The first clue found for a word wins.
+// Clues may refer to other answers as {WORD}; see resolveClue.
+func readClueFiles(paths []string) (dictClues, error) {
+	clues := make(dictClues)
+	for _, path := range paths {
+		if err := clues.load(path); err != nil {
+			return nil, err
+		}
+	}
+	return clues, nil
+}
+
+func (d dictClues) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // WordNet glosses can be long
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		// WordNet files start with an indented licence header
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "  ") {
+			continue
+		}
+		if words, gloss, ok := parseWordNetLine(line); ok {
+			for _, w := range words {
+				d.add(w, gloss)
+			}
+			continue
+		}
+		word, clue, ok := strings.Cut(line, "\t")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected WORD<tab>clue or a WordNet data line", path, n)
+		}
+		d.add(word, strings.TrimSpace(clue))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (d dictClues) add(word, clue string) {
+	key := clueKey(word)
+	if _, ok := d[key]; !ok && clue != "" {
+		d[key] = clue
+	}
+}
+
+func (d dictClues) Clue(word string) (string, error) {
+	if clue, ok := d[clueKey(word)]; ok {
+		return clue, nil
+	}
+	return "", errNoClue
+}
+
+// clueKey normalizes a word for lookup: upper case, letters only, so
+// WordNet's "ice_cream" matches the answer ICECREAM.
+func clueKey(word string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(word) {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseWordNetLine reads a synset line of a WordNet data file:
+//
+//	offset lex_filenum ss_type w_cnt word lex_id [word lex_id...] ... | gloss
+//
+// w_cnt is hexadecimal. The gloss holds the definition followed by
+// "; "-separated quoted examples, which are dropped.
+func parseWordNetLine(line string) (words []string, gloss string, ok bool) {
+	head, gloss, found := strings.Cut(line, " | ")
+	if !found {
+		return nil, "", false
+	}
+	fields := strings.Fields(head)
+	if len(fields) < 4 {
+		return nil, "", false
+	}
+	if _, err := strconv.Atoi(fields[0]); err != nil {
+		return nil, "", false
+	}
+	count, err := strconv.ParseInt(fields[3], 16, 0)
+	if err != nil || len(fields) < 4+2*int(count) {
+		return nil, "", false
+	}
+	for i := 0; i < int(count); i++ {
+		// adjectives may carry a marker such as "(p)"
+		w, _, _ := strings.Cut(fields[4+2*i], "(")
+		words = append(words, w)
+	}
+	definition, _, _ := strings.Cut(gloss, "; \"")
+	return words, strings.TrimSpace(definition), true
+}
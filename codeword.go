@@ -0,0 +1,104 @@
+// file: codeword.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// A codeword is a finished crossword with every letter replaced by a number,
+// the same number for the same letter throughout. There are no clues: the
+// solver starts from a few revealed letters and works out the rest.
+
+// CODEWORD_STARTERS is how many letters are given away at the start.
+const CODEWORD_STARTERS = 2
+
+// Codeword is the coded form of a Puzzle.
+type Codeword struct {
+	puzzle   *Puzzle
+	numbers  map[rune]int // letter -> code, 1 to the number of distinct letters
+	letters  []rune       // code-1 -> letter
+	starters map[rune]bool
+}
+
+// newCodeword assigns the distinct letters of p random codes and picks
+// starters letters to reveal.
+func newCodeword(p *Puzzle, starters int) *Codeword {
+	cw := &Codeword{puzzle: p, numbers: make(map[rune]int), starters: make(map[rune]bool)}
+	for _, ch := range p.grid {
+		if _, ok := cw.numbers[ch]; ch != '#' && !ok {
+			cw.numbers[ch] = 0
+			cw.letters = append(cw.letters, ch)
+		}
+	}
+	// sort before shuffling so the codes depend only on the random source
+	sort.Slice(cw.letters, func(i, j int) bool { return cw.letters[i] < cw.letters[j] })
+	rand.Shuffle(len(cw.letters), func(i, j int) { cw.letters[i], cw.letters[j] = cw.letters[j], cw.letters[i] })
+	for i, ch := range cw.letters {
+		cw.numbers[ch] = i + 1
+	}
+	for _, i := range rand.Perm(len(cw.letters))[:min(starters, len(cw.letters))] {
+		cw.starters[cw.letters[i]] = true
+	}
+	return cw
+}
+
+// Code returns the number of the letter at (r, c), 0 for blocks.
+func (cw *Codeword) Code(r, c int) int {
+	ch := cw.puzzle.Cell(r, c)
+	if ch == '#' {
+		return 0
+	}
+	return cw.numbers[ch]
+}
+
+// writeCodeword prints the coded grid with the starter letters filled in,
+// the key to complete and, with showKey set, the full solution key.
+func writeCodeword(w io.Writer, cw *Codeword, showKey bool) {
+	p := cw.puzzle
+	width := len(fmt.Sprint(len(cw.letters)))
+
+	writeMeta(w, p.Meta)
+	fmt.Fprintln(w, "Codeword:")
+	for r := 0; r < p.Rows; r++ {
+		row := make([]string, p.Cols)
+		for c := 0; c < p.Cols; c++ {
+			switch ch := p.Cell(r, c); {
+			case ch == '#':
+				row[c] = fmt.Sprintf("%*s", width, "#")
+			case cw.starters[ch]:
+				row[c] = fmt.Sprintf("%*c", width, ch)
+			default:
+				row[c] = fmt.Sprintf("%*d", width, cw.numbers[ch])
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, " "))
+	}
+
+	fmt.Fprintln(w, "\nKey:")
+	codes := make([]string, len(cw.letters))
+	given := make([]string, len(cw.letters))
+	for i, ch := range cw.letters {
+		codes[i] = fmt.Sprintf("%*d", width, i+1)
+		given[i] = fmt.Sprintf("%*s", width, "_")
+		if cw.starters[ch] {
+			given[i] = fmt.Sprintf("%*c", width, ch)
+		}
+	}
+	fmt.Fprintln(w, strings.Join(codes, " "))
+	fmt.Fprintln(w, strings.Join(given, " "))
+
+	if !showKey {
+		return
+	}
+	fmt.Fprintln(w, "\nSolution:")
+	solution := make([]string, len(cw.letters))
+	for i, ch := range cw.letters {
+		solution[i] = fmt.Sprintf("%*c", width, ch)
+	}
+	fmt.Fprintln(w, strings.Join(codes, " "))
+	fmt.Fprintln(w, strings.Join(solution, " "))
+}
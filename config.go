@@ -0,0 +1,312 @@
+// file: config.go
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A -config file describes a whole puzzle build in one checked-in document:
+// the requirements.toml keys (size, intersections, iterations, depth and a
+// hints table of answers and their clues), an optional words list of answers
+// without clues, and any command-line flag by its name, such as export,
+// format, out, title or density. Flags given on the command line win over
+// the file.
+//
+// The file is TOML, or YAML if it ends in .yaml or .yml. Only the shapes
+// above are read: top-level keys with strings, numbers or booleans, the
+// hints table and the words list.
+//
+//	size = 15
+//	title = "Cell biology"
+//	export = "ipuz"
+//	words = ["MITOSIS"]
+//
+//	[hints]
+//	MALARIA = "Infectious disease spread by mosquitoes"
+
+// configFile is what a -config file holds, in file order.
+type configFile struct {
+	settings []configSetting
+	hints    [][2]string // answer, clue
+	words    []string
+}
+
+// configSetting is one top-level key of a config file.
+type configSetting struct {
+	key, value string
+	line       int
+}
+
+// readConfig reads the config file at path.
+func readConfig(path string) (*configFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cfg *configFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		cfg, err = parseYAMLConfig(bufio.NewScanner(f))
+	default:
+		cfg, err = parseTOMLConfig(bufio.NewScanner(f))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+	return cfg, nil
+}
+
+// parseTOMLConfig reads the TOML form of a config file. Errors start with
+// the line number.
+func parseTOMLConfig(scanner *bufio.Scanner) (*configFile, error) {
+	cfg := &configFile{}
+	table := ""
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") && !strings.Contains(line, "=") {
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			if table != "hints" {
+				return nil, fmt.Errorf("%d: unknown table [%s] (want [hints])", n, table)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d: expected key = value", n)
+		}
+		key, err := configScalar(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", n, err)
+		}
+		if err := cfg.add(table, key, strings.TrimSpace(value), n); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, scanner.Err()
+}
+
+// parseYAMLConfig reads the YAML form of a config file: top-level
+// "key: value" lines, with hints as an indented block of "ANSWER: clue"
+// lines and words as an indented block of "- ANSWER" lines or a [A, B]
+// list.
+func parseYAMLConfig(scanner *bufio.Scanner) (*configFile, error) {
+	cfg := &configFile{}
+	block := ""
+	for n := 1; scanner.Scan(); n++ {
+		raw := stripComment(scanner.Text())
+		line := strings.TrimSpace(raw)
+		if line == "" || line == "---" {
+			continue
+		}
+		indented := raw[0] == ' ' || raw[0] == '\t'
+		if !indented {
+			block = ""
+		}
+		if indented && block == "words" && strings.HasPrefix(line, "- ") {
+			word, err := configScalar(strings.TrimSpace(line[2:]))
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", n, err)
+			}
+			cfg.words = append(cfg.words, word)
+			continue
+		}
+		key, value, ok := cutYAMLKey(line)
+		if !ok {
+			return nil, fmt.Errorf("%d: expected key: value", n)
+		}
+		key, err := configScalar(key)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", n, err)
+		}
+		switch {
+		case indented && block == "hints":
+			if err := cfg.add("hints", key, value, n); err != nil {
+				return nil, err
+			}
+		case indented:
+			return nil, fmt.Errorf("%d: unexpected indented line (only hints and words hold blocks)", n)
+		case value == "" && (key == "hints" || key == "words"):
+			block = key
+		default:
+			if strings.HasPrefix(value, "[") {
+				// flow list: the TOML array parser reads it as well
+				items := strings.Split(strings.Trim(value, "[]"), ",")
+				for i, item := range items {
+					item = strings.TrimSpace(item)
+					if item != "" && item[0] != '"' && item[0] != '\'' {
+						item = strconv.Quote(item)
+					}
+					items[i] = item
+				}
+				value = "[" + strings.Join(items, ", ") + "]"
+			} else if value != "" && value[0] != '"' && value[0] != '\'' {
+				value = strconv.Quote(value)
+			}
+			if err := cfg.add("", key, value, n); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cfg, scanner.Err()
+}
+
+// cutYAMLKey splits "key: value" at the first colon outside quotes.
+func cutYAMLKey(line string) (key, value string, ok bool) {
+	quote := rune(0)
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ':' && (i+1 == len(line) || line[i+1] == ' '):
+			return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// add records key = value from line n, in table ("" for the top level).
+// value is still in its written form.
+func (cfg *configFile) add(table, key, value string, n int) error {
+	if table == "hints" {
+		clue, err := configScalar(value)
+		if err != nil {
+			return fmt.Errorf("%d: %w", n, err)
+		}
+		cfg.hints = append(cfg.hints, [2]string{key, clue})
+		return nil
+	}
+	if key == "words" {
+		if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+			return fmt.Errorf("%d: words must be a list", n)
+		}
+		for _, item := range strings.Split(value[1:len(value)-1], ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			word, err := configScalar(item)
+			if err != nil {
+				return fmt.Errorf("%d: %w", n, err)
+			}
+			cfg.words = append(cfg.words, word)
+		}
+		return nil
+	}
+	value, err := configScalar(value)
+	if err != nil {
+		return fmt.Errorf("%d: %w", n, err)
+	}
+	cfg.settings = append(cfg.settings, configSetting{key, value, n})
+	return nil
+}
+
+// configScalar returns the value of a written scalar: a double-quoted
+// string with escapes, a single-quoted literal string, or bare text such as
+// a number, a boolean or a key.
+func configScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("bad string %s", s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("bad string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	}
+	return s, nil
+}
+
+// stripComment drops a # comment outside quotes from line.
+func stripComment(line string) string {
+	quote, escaped := byte(0), false
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && ch == '\\':
+			escaped = true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// loadConfig applies the -config file to c: its settings fill in the flags
+// fs did not get on the command line, and its hints and words replace the
+// built-in word list.
+func (c *cli) loadConfig(fs *flag.FlagSet) error {
+	cfg, err := readConfig(c.config)
+	if err != nil {
+		return err
+	}
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	sizeSet, intersectionsSet := false, false
+	for _, s := range cfg.settings {
+		// the requirements.toml keys have no flags
+		target := map[string]*int{"size": &c.gridSize, "intersections": &c.reqIntersections, "iterations": &c.maxIter, "depth": &c.maxDepth}[s.key]
+		switch {
+		case target != nil:
+			// an intersection requirement of 0 turns it off; the rest need 1 or more
+			least := 1
+			if s.key == "intersections" {
+				least = 0
+			}
+			n, err := strconv.Atoi(s.value)
+			if err != nil || n < least {
+				return fmt.Errorf("%s:%d: %s must be a whole number of at least %d, not %q", c.config, s.line, s.key, least, s.value)
+			}
+			*target = n
+			sizeSet = sizeSet || s.key == "size"
+			intersectionsSet = intersectionsSet || s.key == "intersections"
+		case s.key == "config" || fs.Lookup(s.key) == nil:
+			return fmt.Errorf("%s:%d: unknown setting %q", c.config, s.line, s.key)
+		case given[s.key]:
+		default:
+			if err := fs.Set(s.key, s.value); err != nil {
+				return fmt.Errorf("%s:%d: %s: %w", c.config, s.line, s.key, err)
+			}
+		}
+	}
+	// as in requirements.toml, the requirement follows the size unless given
+	if sizeSet && !intersectionsSet {
+		c.reqIntersections = max(c.gridSize-3, 0)
+	}
+	if len(cfg.hints) > 0 || len(cfg.words) > 0 {
+		c.words = nil
+		c.configClues = make(dictClues)
+		for _, h := range cfg.hints {
+			c.words = append(c.words, h[0])
+			c.configClues.add(h[0], h[1])
+		}
+		c.words = append(c.words, cfg.words...)
+	}
+	return nil
+}
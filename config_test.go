@@ -0,0 +1,79 @@
+// file: config_test.go
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(*bufio.Scanner) (*configFile, error)
+		doc   string
+		want  *configFile
+		err   string // prefix of the error; "" for none
+	}{
+		{"TOML", parseTOMLConfig, `size = 15
+title = "Cell biology"  # comment
+export = 'ipuz'
+words = ["MITOSIS", 'MEIOSIS']
+
+[hints]
+MALARIA = "Infectious disease spread by mosquitoes"
+"GOOD-LOOKING" = "Handsome # not a comment"
+`, &configFile{
+			settings: []configSetting{{"size", "15", 1}, {"title", "Cell biology", 2}, {"export", "ipuz", 3}},
+			hints:    [][2]string{{"MALARIA", "Infectious disease spread by mosquitoes"}, {"GOOD-LOOKING", "Handsome # not a comment"}},
+			words:    []string{"MITOSIS", "MEIOSIS"},
+		}, ""},
+		{"TOML escapes", parseTOMLConfig, `title = "Say \"hi\" # twice"`, &configFile{
+			settings: []configSetting{{"title", `Say "hi" # twice`, 1}},
+		}, ""},
+		{"TOML unknown table", parseTOMLConfig, "size = 9\n[meta]\n", nil, "2: unknown table [meta]"},
+		{"TOML no equals", parseTOMLConfig, "size 15", nil, "1: expected key = value"},
+		{"TOML words not a list", parseTOMLConfig, `words = "CAT"`, nil, "1: words must be a list"},
+		{"TOML open string", parseTOMLConfig, `title = "Cell`, nil, "1: bad string"},
+		{"YAML", parseYAMLConfig, `---
+size: 15
+title: Cell biology # comment
+notes: "Ratio: 2:1"
+words:
+  - MITOSIS
+  - 'MEIOSIS'
+hints:
+  MALARIA: Infectious disease
+  "GOOD-LOOKING": 'Handsome'
+`, &configFile{
+			settings: []configSetting{{"size", "15", 2}, {"title", "Cell biology", 3}, {"notes", "Ratio: 2:1", 4}},
+			hints:    [][2]string{{"MALARIA", "Infectious disease"}, {"GOOD-LOOKING", "Handsome"}},
+			words:    []string{"MITOSIS", "MEIOSIS"},
+		}, ""},
+		{"YAML flow list", parseYAMLConfig, `words: [CAT, "SEA LION", 'DOG']`, &configFile{
+			words: []string{"CAT", "SEA LION", "DOG"},
+		}, ""},
+		{"YAML stray indent", parseYAMLConfig, "size: 9\n  title: x\n", nil, "2: unexpected indented line"},
+		{"YAML no colon", parseYAMLConfig, "size 15", nil, "1: expected key: value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(bufio.NewScanner(strings.NewReader(tt.doc)))
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
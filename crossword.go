@@ -2,10 +2,14 @@
 package main
 
 import (
+	"cmp"
 	"fmt"
+	"io"
+	"log/slog"
 	"math/rand"
-	"strings"
-	"github.com/cheggaaa/pb/v3"
+	"slices"
+	"sort"
+	"time"
 )
 
 type Pos struct {
@@ -13,26 +17,173 @@ type Pos struct {
 }
 
 type Placement struct {
-	Loc  int    // encoded start position: R*gridSize + C
-	Word string // placed word
+	Loc   int    // encoded start position: R*gridSize + C
+	Word  string // placed word
+	Order int    // position in the placement sequence, 0 for the first word
 }
 
 const (
 	HORIZONTAL = 0
 	VERTICAL   = 1
+
+	// further directions, used by word searches and, through
+	// Options.Directions, by novelty crosswords
+	LEFTWARD   = 2 // right to left
+	UPWARD     = 3 // bottom to top
+	DOWN_RIGHT = 4
+	UP_LEFT    = 5
+	DOWN_LEFT  = 6
+	UP_RIGHT   = 7
+)
+
+// directionSteps is the offset from one letter of a word to the next.
+var directionSteps = [...]Pos{
+	HORIZONTAL: {0, 1},
+	VERTICAL:   {1, 0},
+	LEFTWARD:   {0, -1},
+	UPWARD:     {-1, 0},
+	DOWN_RIGHT: {1, 1},
+	UP_LEFT:    {-1, -1},
+	DOWN_LEFT:  {1, -1},
+	UP_RIGHT:   {-1, 1},
+}
+
+// directionAxes groups each direction with its reverse. Words crossing at a
+// cell must lie on different axes.
+var directionAxes = [...]int{
+	HORIZONTAL: 0,
+	LEFTWARD:   0,
+	VERTICAL:   1,
+	UPWARD:     1,
+	DOWN_RIGHT: 2,
+	UP_LEFT:    2,
+	DOWN_LEFT:  3,
+	UP_RIGHT:   3,
+}
+
+// checkDirections fails if crossword words in dirs could never cross, i.e.
+// they all lie on one axis.
+func checkDirections(dirs []int) error {
+	if dirs != nil && len(crossingDirections(dirs, dirs[0])) == 0 {
+		return fmt.Errorf("crossword directions must include two that are not the reverse of each other")
+	}
+	return nil
+}
+
+// crossingDirections returns the directions of dirs a word may take when it
+// crosses a word placed in direction.
+func crossingDirections(dirs []int, direction int) []int {
+	var out []int
+	for _, d := range dirs {
+		if directionAxes[d] != directionAxes[direction] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Options controls a generation run.
+type Options struct {
+	GridSize         int
+	ReqIntersections int              // minimum required intersecting cells
+	MaxIter          int              // number of shuffles to try
+	MaxDepth         int              // placements tried per shuffle; the cap with a growing Restart schedule
+	Restart          string           // depth budget schedule across shuffles: "fixed" (or ""), "luby" or "geometric"
+	RestartUnit      int              // budget of the first shuffle with a growing schedule
+	Strategy         string           // search algorithm, see strategies: "dfs" (or "")
+	MinDensity       float64          // minimum percentage of grid cells holding a letter; 0 disables
+	MaxEmpty         int              // maximum empty cells inside the words' bounding box; 0 disables
+	MinCrossings     int              // every word must cross at least this many others (freeform grids); 0 disables
+	FoldAccents      bool             // place É as E, Ñ as N etc.; entries keep the accented form
+	Language         string           // language profile, see lang.go; "" for the default
+	AllowIslands     bool             // accept grids whose words form several unconnected groups
+	MostConstrained  bool             // at each level try first the words with the fewest places to go
+	MemoMB           int              // memory for remembering failed partial grids across shuffles; 0 disables
+	Directions       []int            // directions words may run in, the first word taking the first; nil for across and down
+	Progress         Progress         // nil reports nothing
+	Stats            *GenerationStats // if set, generation adds what it did to it
+	Trace            PlacementTrace   // if set, every placement tried is recorded in it
+	Tree             *SearchTree      // if set, the search tree is recorded in it
+	Replay           *Replay          // if set, the construction of the best layout is recorded in it
+	Logger           *slog.Logger     // generation events are logged at debug level; nil discards
+}
+
+// Defaults of a run, shared by the CLI, Generate and the browser build.
+const (
+	DEFAULT_SIZE         = 14     // grid size
+	DEFAULT_ITERATIONS   = 2000   // shuffles tried
+	DEFAULT_DEPTH        = 100000 // placements tried per shuffle
+	DEFAULT_RESTART_UNIT = 1000   // first budget of a growing restart schedule
+	DEFAULT_MEMO_MB      = 64     // memory for remembering dead ends
 )
 
-func main() {
-	// === user-editable inputs ===
-	gridSize := 14
-	reqIntersections := 12    // minimum required intersecting cells
-	MAX_ITER := 2000          // number of shuffles to try
-	MAX_DEPTH := 100000      // recursion placement limit (global)
-	words := []string{
-		"INTEGRINS", "ANGIOGENESIS", "ALLOSTASIS", "INFLAMMATION", "ASTROCYTES", "MICROGLIA",
-		"MICROGLIA", "HYPOXIA", "MALARIA", "VIRULENCE", "PARKINSON",
+// generate searches for the layout of words with the most intersections,
+// stopping early once opts.ReqIntersections and the density limits are met.
+// Returns nil if nothing could be produced. opts.Strategy picks the search.
+func generate(words []string, opts Options) *Puzzle {
+	s, ok := strategies[opts.Strategy]
+	if !ok {
+		s = strategies["dfs"]
+	}
+	return s.Search(words, opts, newRand())
+}
+
+// candidate is a layout found by the search, with what ranks it.
+type candidate struct {
+	p         *Puzzle
+	met       bool // placed every word and meets the requirements
+	connected bool
+	dense     bool
+}
+
+// beats reports whether a ranks above b: meeting the requirements first,
+// then a connected grid over islands, then the most intersections, and on
+// a tie a grid dense enough.
+func (a candidate) beats(b candidate) bool {
+	if a.met != b.met {
+		return a.met
+	}
+	if a.connected != b.connected {
+		return a.connected
+	}
+	if a.p.Intersections() != b.p.Intersections() {
+		return a.p.Intersections() > b.p.Intersections()
+	}
+	return a.dense && !b.dense
+}
+
+// generateTop is generate keeping the k best layouts, best first, where
+// layouts differing only by a rotation, reflection or transposition count
+// once. It stops early once k layouts meet the requirements. Mini and giant
+// grids give a single puzzle.
+func generateTop(words []string, k int, opts Options, rng *rand.Rand) []*Puzzle {
+	gridSize := opts.GridSize
+	progress := opts.Progress
+	if progress == nil {
+		progress = noProgress{}
+	}
+	defer progress.OnDone()
+	if opts.Replay != nil {
+		*opts.Replay = Replay{Size: gridSize}
+	}
+	if opts.Stats != nil {
+		defer func(start time.Time) { opts.Stats.WallTime += time.Since(start) }(time.Now())
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	words = append([]string(nil), words...)
+	// fold accents and drop word breaks before sorting, since both change word
+	// lengths (ß -> SS, BLOOD BRAIN -> BLOODBRAIN)
+	display := make(map[string]string) // placed word -> word as written in the input
+	for i, w := range words {
+		words[i] = gridForm(w, opts.folds())
+		if words[i] != w {
+			display[words[i]] = w
+		}
 	}
-	// ============================
 
 	// sort words by length descending (like Julia code)
 	// simple bubble-ish sort for clarity
@@ -44,73 +195,128 @@ func main() {
 		}
 	}
 
-	var bestGrid map[Pos]rune
-	// var bestCellDir map[Pos]string
-	var bestClassification map[int][]Placement
-	bestIntersections := -1
-	bar := pb.StartNew(MAX_ITER)
-	for iter := 0; iter < MAX_ITER; iter++ {
+	// minis get a dense, fully checked fill; fall back to the freeform search if
+	// it fails or misses the requirements. The mini fill records no stats, trace
+	// or replay, so runs asking for one use the freeform search too. Mini fills
+	// and tiles are across and down only, so other directions always use the
+	// freeform search.
+	recorded := opts.Stats != nil || opts.Trace != nil || opts.Tree != nil || opts.Replay != nil
+	if gridSize <= MINI_MAX_SIZE && opts.Directions == nil && !recorded {
+		if best := generateMini(words, gridSize, opts.MaxDepth, display, rng); best != nil && opts.met(best) {
+			logger.Debug("mini fill succeeded", "intersections", best.Intersections())
+			// a mini uses the words fitting its pattern and leaves out the rest
+			used := make(map[string]bool)
+			for _, e := range best.entries() {
+				used[e.Word] = true
+			}
+			for _, w := range words {
+				if !used[w] {
+					written := w
+					if d, ok := display[w]; ok {
+						written = d
+					}
+					logger.Warn("word left out of the mini", "word", written)
+				}
+			}
+			return []*Puzzle{best}
+		}
+		logger.Debug("mini fill failed or missed the requirements, falling back to freeform search")
+	}
+	// giant grids are filled tile by tile instead of in one recursion
+	if gridSize >= GIANT_GRID_SIZE && opts.Directions == nil {
+		logger.Debug("filling giant grid in tiles", "tile", TILE_SIZE, "overlap", TILE_OVERLAP)
+		if p := generateTiled(words, gridSize, TILE_SIZE, TILE_OVERLAP, opts.MaxDepth, display, rng, logger); p != nil {
+			return []*Puzzle{p}
+		}
+		return nil
+	}
+
+	var top []candidate
+	keys := make(map[string]bool)
+	memo := newFailMemo(opts.MemoMB)
+	for iter := 0; iter < opts.MaxIter; iter++ {
 		// shuffle copy of words
 		shuffled := make([]string, len(words))
 		copy(shuffled, words)
-		rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
-		// shuffled = []string{"ANGIOGENESIS", "ALLOSTASIS", "INFLAMMATION", "INTEGRINS", "MICROGLIA", "ASTROCYTES"}
-		// fmt.Println(shuffled)
-
-		// initialize containers for createGrid
-		grid := initGrid(gridSize)
-		cellDir := initCellDir(gridSize)
-		connections := initConnections(gridSize)
-		classification := map[int][]Placement{0: {}, 1: {}}
-		depth := 0
-
-		accept, intersections := createGrid(&grid, shuffled, gridSize, HORIZONTAL, &cellDir, &classification, &depth, &connections, MAX_DEPTH, reqIntersections)
-		if accept && intersections >= reqIntersections {
-			bestGrid = grid
-			// bestCellDir = cellDir
-			bestClassification = classification
-			bestIntersections = intersections
-			// we found one satisfying the requirement; stop early
-			break
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+		budget := opts.budget(iter)
+		search := newSearch(shuffled, gridSize, opts.Directions, budget, opts.MinCrossings)
+		search.mostConstrained = opts.MostConstrained
+		search.memo = memo
+		search.trace = opts.Trace
+		if opts.Tree != nil && len(search.stack) > 0 {
+			search.tree = opts.Tree
+			search.stack[0].node = opts.Tree.add(-1, fmt.Sprintf("shuffle %d", iter+1), NODE_ROOT, ACCEPTED)
 		}
-		// keep the one with max intersections so far
-		if intersections > bestIntersections {
-			bestGrid = grid
-			// bestCellDir = cellDir
-			bestClassification = classification
-			bestIntersections = intersections
+		if opts.Replay != nil {
+			search.replay = &Replay{Size: gridSize}
+		}
+		search.run(0)
+		accept, intersections := search.accepted, search.index.crossings
+		logger.Debug("iteration", "iter", iter, "accepted", accept, "intersections", intersections, "depth", search.depth)
+		if search.depth > budget {
+			logger.Debug("depth exhausted", "iter", iter, "budget", budget)
+		}
+		if st := opts.Stats; st != nil {
+			st.Iterations++
+			st.Placements += min(search.depth, budget)
+			st.Backtracks += search.backtracks
+			if search.depth > budget {
+				st.Exhausted++
+			}
+		}
+		found := candidate{p: newPuzzle(gridSize, search.grid, search.classification(), display)}
+		found.dense = opts.dense(found.p)
+		found.connected = opts.connected(found.p)
+		found.met = accept && intersections >= opts.ReqIntersections && found.dense && found.connected
+		if key := found.p.CanonicalHash(); !keys[key] {
+			at := len(top)
+			for at > 0 && found.beats(top[at-1]) {
+				at--
+			}
+			if at < k {
+				keys[key] = true
+				top = append(top[:at], append([]candidate{found}, top[at:]...)...)
+				if at == 0 && opts.Replay != nil {
+					*opts.Replay = *search.replay
+				}
+				if len(top) > k {
+					delete(keys, top[k].p.CanonicalHash())
+					top = top[:k]
+				}
+				logger.Debug("kept layout", "iter", iter, "rank", at+1, "intersections", intersections, "density", found.p.Density())
+			}
+		}
+		progress.OnIteration(iter, top[0].p.Intersections())
+		if opts.Stats != nil {
+			opts.Stats.BestScores = append(opts.Stats.BestScores, top[0].p.Intersections())
+		}
+		if len(top) == k && top[k-1].met {
+			// the worst kept layout meets the requirements, so all do; stop early
+			logger.Debug("requirement met", "iter", iter, "kept", k)
+			break
 		}
-		bar.Increment()
 	}
-	bar.Finish()
 
-	if bestGrid == nil {
-		fmt.Println("No valid crossword produced.")
-		return
+	out := make([]*Puzzle, len(top))
+	for i, c := range top {
+		out[i] = c.p
 	}
+	return out
+}
 
-	// print grid
-	fmt.Println("Crossword:")
-	for r := 0; r < gridSize; r++ {
-		row := make([]string, gridSize)
-		for c := 0; c < gridSize; c++ {
-			ch := bestGrid[Pos{r, c}]
-			row[c] = string(ch)
-		}
-		fmt.Println(strings.Join(row, " "))
+// dense reports whether p meets the MinDensity and MaxEmpty limits.
+func (opts Options) dense(p *Puzzle) bool {
+	if opts.MinDensity > 0 && p.Density() < opts.MinDensity {
+		return false
 	}
-	fmt.Printf("\nIntersections: %d\n", bestIntersections)
+	return opts.MaxEmpty <= 0 || p.BoundingEmpty() <= opts.MaxEmpty
+}
 
-	// print classification (down=0? We used 0=horizontal,1=vertical similar to Julia where classification[0] likely down)
-	fmt.Println("\nClassification:")
-	fmt.Println("Across:")
-	for _, p := range bestClassification[HORIZONTAL] {
-		fmt.Printf("  %d -> %s\n", p.Loc, p.Word)
-	}
-	fmt.Println("Down:")
-	for _, p := range bestClassification[VERTICAL] {
-		fmt.Printf("  %d -> %s\n", p.Loc, p.Word)
-	}
+// connected reports whether p's letters form a single group, or islands are allowed.
+func (opts Options) connected(p *Puzzle) bool {
+	return opts.AllowIslands || len(p.components()) <= 1
 }
 
 // --- initializers
@@ -134,69 +340,42 @@ func initCellDir(size int) map[Pos]string {
 	return cd
 }
 
-func initConnections(size int) map[Pos][]Pos {
-	conn := make(map[Pos][]Pos)
-	for r := 0; r < size; r++ {
-		for c := 0; c < size; c++ {
-			p := Pos{r, c}
-			conn[p] = []Pos{p}
-		}
-	}
-	return conn
-}
-
 // --- getSequence
 func getSequence(head Pos, direction int, word string) []Pos {
 	runes := []rune(word)
 	seq := make([]Pos, len(runes))
-	if direction == HORIZONTAL {
-		for i := range runes {
-			seq[i] = Pos{head.R + i, head.C}
-		}
-	} else {
-		for i := range runes {
-			seq[i] = Pos{head.R, head.C + i}
-		}
+	step := directionSteps[direction]
+	for i := range runes {
+		seq[i] = Pos{head.R + i*step.R, head.C + i*step.C}
 	}
 	return seq
 }
 
 // --- isAcceptable
-func isAcceptable(word string, sequence []Pos, direction int, crossword map[Pos]rune, cellDirection map[Pos]string, gridSize int, connections map[Pos][]Pos) bool {
+func isAcceptable(word string, sequence []Pos, direction int, crossword map[Pos]rune, cellDirection map[Pos]string, gridSize int, index *gridIndex) bool {
+	return rejection(word, sequence, direction, crossword, cellDirection, gridSize, index) == ACCEPTED
+}
+
+// rejection is isAcceptable returning why a placement is refused, one of
+// the REJECT_ reasons (see explain.go), or ACCEPTED.
+func rejection(word string, sequence []Pos, direction int, crossword map[Pos]rune, cellDirection map[Pos]string, gridSize int, index *gridIndex) int {
 	runes := []rune(word)
 	// 1. Boundary check
 	last := sequence[len(sequence)-1]
 	first := sequence[0]
-	if last.R >= gridSize || last.C >= gridSize || first.R < 0 || first.C < 0 {
-		return false
+	for _, end := range []Pos{first, last} {
+		if end.R < 0 || end.C < 0 || end.R >= gridSize || end.C >= gridSize {
+			return REJECT_BOUNDARY
+		}
 	}
 
 	// 2. Adjacent check: ensure word doesn't touch other words from head/tail
-	for _, shift := range []int{0, -1} {
-		var adjacent Pos
-		if shift == 0 {
-			adjacent = sequence[0]
-		} else {
-			adjacent = sequence[len(sequence)-1]
-		}
-		// move two cells backwards/forwards along direction
-		if shift == 0 {
-			if direction == HORIZONTAL {
-				adjacent = Pos{adjacent.R - 1, adjacent.C}
-			} else {
-				adjacent = Pos{adjacent.R, adjacent.C - 1}
-			}
-		} else {
-			if direction == HORIZONTAL {
-				adjacent = Pos{adjacent.R + 1, adjacent.C}
-			} else {
-				adjacent = Pos{adjacent.R, adjacent.C + 1}
-			}
-		}
+	step := directionSteps[direction]
+	for _, adjacent := range []Pos{{first.R - step.R, first.C - step.C}, {last.R + step.R, last.C + step.C}} {
 		// check bounds and occupancy
 		if adjacent.R >= 0 && adjacent.R < gridSize && adjacent.C >= 0 && adjacent.C < gridSize {
 			if crossword[adjacent] != '#' {
-				return false
+				return REJECT_ADJACENT
 			}
 		}
 	}
@@ -204,19 +383,19 @@ func isAcceptable(word string, sequence []Pos, direction int, crossword map[Pos]
 	// 3. Per-character checks
 	for idx, loc := range sequence {
 		char := runes[idx]
-		// Ensure no illegal touching left/right (if vertical) or up/down (if horizontal)
-		for _, shift := range []int{-1, 1} {
-			var adjacent Pos
-			if direction == HORIZONTAL {
-				adjacent = Pos{loc.R, loc.C + shift}
-			} else {
-				adjacent = Pos{loc.R + shift, loc.C}
+		// Ensure no illegal touching: every letter beside the word, other than
+		// along it, must belong to a word already running through both cells.
+		// Diagonal words are checked on all four sides, so they never make
+		// runs of letters that are not entries.
+		for _, side := range []Pos{{0, 1}, {1, 0}, {0, -1}, {-1, 0}} {
+			if side == step || side == (Pos{-step.R, -step.C}) {
+				continue
 			}
+			adjacent := Pos{loc.R + side.R, loc.C + side.C}
 			if adjacent.R >= 0 && adjacent.R < gridSize && adjacent.C >= 0 && adjacent.C < gridSize {
 				if crossword[adjacent] != '#' {
-					// if loc is not part of connections[adjacent], then illegal touching
-					if !posInSlice(loc, connections[adjacent]) {
-						return false
+					if !index.shareWord(loc, adjacent) {
+						return REJECT_ADJACENT
 					}
 				}
 			}
@@ -225,97 +404,94 @@ func isAcceptable(word string, sequence []Pos, direction int, crossword map[Pos]
 		// Ensure overlaps match existing letters and directions
 		if crossword[loc] != '#' {
 			if crossword[loc] != char {
-				return false
+				return REJECT_MISMATCH
 			}
-			// cellDirection should be the opposite direction (like Julia check)
+			// a crossing joins exactly two words on different axes
 			existing := cellDirection[loc]
-			expected := fmt.Sprintf("%d", 1-direction)
-			// if existing is not exactly expected (because Julia required equal string), reject
-			// In Julia they do: cell_direction[loc] != string(1 - direction)
-			// We'll enforce the same: existing must equal opposite direction when overlap happens.
-			if existing != expected {
-				return false
+			if len(existing) != 1 || directionAxes[int(existing[0]-'0')] == directionAxes[direction] {
+				return REJECT_DIRECTION
 			}
 		}
 	}
-	// deltas := []Pos{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
-	// for _, loc := range sequence {
-	// 	for _, d := range deltas {
-	// 		nb := Pos{loc.R + d.R, loc.C + d.C}
-	// 		if nb.R < 0 || nb.R >= gridSize || nb.C < 0 || nb.C >= gridSize {
-	// 			continue
-	// 		}
-	// 		if crossword[nb] != '#' {
-	// 			// neighbor occupied but not intersecting -> invalid
-	// 			if !posInSlice(nb, connections[loc]) {
-	// 				return false
-	// 			}
-	// 		}
-	// 	}
-	// }
+	return ACCEPTED
+}
 
-	return true
+// --- intersectingHead
+// gridIndex is kept in step with the grid by addToGrid and removeFromGrid,
+// so the search can answer its frequent questions without scanning the
+// grid: which cells hold a letter, and how many cells are crossings.
+type gridIndex struct {
+	letters   map[rune]map[Pos]bool
+	crossings int // cells used in both directions
+	words     map[Pos][]wordRef
+	nextID    int
+	hash      uint64 // Zobrist hash of the placed letters and directions, see memo.go
+}
+
+// wordRef names the placed word covering a cell in one direction. IDs are
+// only compared, never reused within an index.
+type wordRef struct {
+	id        int
+	direction int
+}
+
+func newGridIndex() *gridIndex {
+	return &gridIndex{letters: make(map[rune]map[Pos]bool), words: make(map[Pos][]wordRef)}
 }
 
-func posInSlice(p Pos, list []Pos) bool {
-	for _, q := range list {
-		if q == p {
-			return true
+// shareWord reports whether a and b are covered by the same placed word.
+func (gi *gridIndex) shareWord(a, b Pos) bool {
+	for _, x := range gi.words[a] {
+		for _, y := range gi.words[b] {
+			if x.id == y.id {
+				return true
+			}
 		}
 	}
 	return false
 }
 
-// --- intersectingHead
-func intersectingHead(word string, direction int, cellDirection map[Pos]string, crossword map[Pos]rune, gridSize int) []Pos {
-	// If grid empty (all '#'), return (0,0)
-	allEmpty := true
-	for _, v := range crossword {
-		if v != '#' {
-			allEmpty = false
-			break
+// empty reports whether no cell holds a letter.
+func (gi *gridIndex) empty() bool {
+	for _, cells := range gi.letters {
+		if len(cells) > 0 {
+			return false
 		}
 	}
-	if allEmpty {
+	return true
+}
+
+// intersectingHead returns the heads at which word, laid in direction, puts
+// its first occurrence of some letter on a cell already holding that letter
+// and not yet used on direction's axis. On an empty grid the only head is
+// (0, 0).
+func intersectingHead(word string, direction int, cellDirection map[Pos]string, index *gridIndex) []Pos {
+	if index.empty() {
 		return []Pos{{0, 0}}
 	}
 
 	var allowed []Pos
 	runes := []rune(word)
-	for k, v := range crossword {
-		// Must intersect with a matching character
-		if !runeInRunes(v, runes) {
-			continue
-		}
-		// Skip if direction already occupied at that cell
-		if strings.Contains(cellDirection[k], fmt.Sprintf("%d", direction)) {
-			continue
-		}
-		// find first matching index in word (like Julia's findfirst)
-		matchIdx := indexOfRuneInRunes(v, runes)
-		if matchIdx == -1 {
+	step := directionSteps[direction]
+	for idx, r := range runes {
+		// only the first occurrence of a letter in the word (like Julia's findfirst)
+		if indexOfRuneInRunes(r, runes) != idx {
 			continue
 		}
-		// matchIdx is 0-based; Julia used 1-based match so subtract accordingly
-		if direction == HORIZONTAL {
-			head := Pos{k.R - matchIdx, k.C}
-			allowed = append(allowed, head)
-		} else {
-			head := Pos{k.R, k.C - matchIdx}
-			allowed = append(allowed, head)
+		start := len(allowed)
+		for loc := range index.letters[r] {
+			// Skip if a word on the same axis already runs through the cell
+			if existing := cellDirection[loc]; existing != "" && directionAxes[int(existing[0]-'0')] == directionAxes[direction] {
+				continue
+			}
+			allowed = append(allowed, Pos{loc.R - idx*step.R, loc.C - idx*step.C})
 		}
+		// in grid order rather than map order, so a seeded search repeats
+		slices.SortFunc(allowed[start:], func(a, b Pos) int { return cmp.Or(a.R-b.R, a.C-b.C) })
 	}
 	return allowed
 }
 
-func runeInRunes(r rune, arr []rune) bool {
-	for _, x := range arr {
-		if x == r {
-			return true
-		}
-	}
-	return false
-}
 func indexOfRuneInRunes(r rune, arr []rune) int {
 	for i, x := range arr {
 		if x == r {
@@ -326,122 +502,345 @@ func indexOfRuneInRunes(r rune, arr []rune) int {
 }
 
 // --- addToGrid / removeFromGrid
-func addToGrid(word string, sequence []Pos, direction int, grid map[Pos]rune, cellDirection map[Pos]string, connections map[Pos][]Pos) {
+func addToGrid(word string, sequence []Pos, direction int, grid map[Pos]rune, cellDirection map[Pos]string, index *gridIndex) {
 	runes := []rune(word)
+	index.nextID++
 	for idx, loc := range sequence {
 		grid[loc] = runes[idx]
+		if index.letters[runes[idx]] == nil {
+			index.letters[runes[idx]] = make(map[Pos]bool)
+		}
+		index.letters[runes[idx]][loc] = true
+		index.hash ^= cellHash(loc, runes[idx], direction)
 		// append the direction char to the cellDirection string (mimic Julia string concat)
 		cellDirection[loc] = cellDirection[loc] + fmt.Sprintf("%d", direction)
-		// update connections
-		for _, loc2 := range sequence {
-			if loc2 != loc {
-				connections[loc] = append(connections[loc], loc2)
-			}
+		if len(cellDirection[loc]) == 2 {
+			index.crossings++
 		}
+		index.words[loc] = append(index.words[loc], wordRef{index.nextID, direction})
 	}
 }
 
-func removeFromGrid(word string, sequence []Pos, direction int, grid map[Pos]rune, cellDirection map[Pos]string, connections map[Pos][]Pos) {
-	// revert placement similar to Julia:
-	// pop connections for each loc (length(sequence)-1) times
+func removeFromGrid(word string, sequence []Pos, direction int, grid map[Pos]rune, cellDirection map[Pos]string, index *gridIndex) {
 	for _, loc := range sequence {
-		// pop last (length(sequence)-1) entries
-		removeCount := len(sequence) - 1
-		if removeCount > len(connections[loc]) {
-			connections[loc] = []Pos{loc}
-		} else {
-			connections[loc] = connections[loc][:len(connections[loc])-removeCount]
+		index.hash ^= cellHash(loc, grid[loc], direction)
+		// a cell holds at most one word per direction
+		refs := index.words[loc][:0]
+		for _, ref := range index.words[loc] {
+			if ref.direction != direction {
+				refs = append(refs, ref)
+			}
 		}
+		index.words[loc] = refs
 		if len(cellDirection[loc]) == 1 {
+			delete(index.letters[grid[loc]], loc)
 			grid[loc] = '#'
 			cellDirection[loc] = ""
 		} else {
 			// drop last char
+			if len(cellDirection[loc]) == 2 {
+				index.crossings--
+			}
 			cellDirection[loc] = cellDirection[loc][:len(cellDirection[loc])-1]
 		}
 	}
 }
 
-// --- createGrid (recursive backtracking)
-func createGrid(grid *map[Pos]rune, wordsList []string, gridSize int, direction int, cellDirection *map[Pos]string,
-	classification *map[int][]Placement, depth *int, connections *map[Pos][]Pos, MAX_DEPTH int, reqIntersections int) (bool, int) {
+// --- crossing bookkeeping
+// crossingBook records which words cover each cell and how many other words
+// every placed word crosses, so the search can reject dangling words.
+type crossingBook struct {
+	cells map[Pos][]string
+	count map[string]int
+}
 
-	// if depth == 0: initialization already done by caller in this Go version
+func newCrossingBook() *crossingBook {
+	return &crossingBook{cells: make(map[Pos][]string), count: make(map[string]int)}
+}
 
-	// Helper to count intersections
-	countIntersections := func() int {
-		cnt := 0
-		for _, v := range *cellDirection {
-			if len(v) > 1 {
-				cnt++
-			}
+func (b *crossingBook) add(word string, sequence []Pos) {
+	b.count[word] = 0
+	for _, loc := range sequence {
+		for _, other := range b.cells[loc] {
+			b.count[other]++
+			b.count[word]++
 		}
-		return cnt
+		b.cells[loc] = append(b.cells[loc], word)
 	}
+}
 
-	// iterate over words
-	for _, word := range wordsList {
-		// allowedHeads
-		var allowedHeads []Pos
-		if allGridEmpty(*grid) {
-			allowedHeads = []Pos{}
-			// produce all cells (Julia used all cells first time)
-			for r := 0; r < gridSize; r++ {
-				for c := 0; c < gridSize; c++ {
-					allowedHeads = append(allowedHeads, Pos{r, c})
-				}
+func (b *crossingBook) remove(word string, sequence []Pos) {
+	for _, loc := range sequence {
+		b.cells[loc] = b.cells[loc][:len(b.cells[loc])-1]
+		for _, other := range b.cells[loc] {
+			b.count[other]--
+		}
+	}
+	delete(b.count, word)
+}
+
+// satisfied reports whether every placed word crosses at least min others.
+func (b *crossingBook) satisfied(min int) bool {
+	for _, n := range b.count {
+		if n < min {
+			return false
+		}
+	}
+	return true
+}
+
+// --- search (backtracking with an explicit stack)
+// The search places words one per level, alternating across and down (or,
+// with more directions, turning to another axis at each level). Each
+// level is a frame on the stack instead of a recursive call, so deep word
+// lists cannot overflow the goroutine stack and a search can be paused after
+// any number of steps and resumed later.
+//
+// With minCrossings > 0 a complete layout is only accepted once every word
+// crosses that many others; words placed later can still cross earlier ones,
+// so the check waits for the last word.
+
+// searchState is one backtracking search over a grid. The grid maps may be
+// seeded (see seedTile) before the first step.
+type searchState struct {
+	grid            map[Pos]rune
+	cellDirection   map[Pos]string
+	index           *gridIndex
+	book            *crossingBook
+	gridSize        int
+	directions      []int
+	maxDepth        int // placements tried before the search gives up
+	minCrossings    int
+	mostConstrained bool           // order each level's words by their number of viable heads
+	memo            *failMemo      // levels known to fail; nil for none
+	depth           int            // placements tried so far
+	backtracks      int            // levels given up
+	trace           PlacementTrace // nil traces nothing
+	tree            *SearchTree    // nil records nothing
+	replay          *Replay        // nil records nothing
+	stack           []searchFrame
+	finished        bool
+	accepted        bool
+}
+
+// searchFrame is one level of the search: the words left to place there, the
+// directions they may take, and how far through their heads it has got.
+type searchFrame struct {
+	words      []string
+	directions []int
+	direction  int   // directions[dir], the direction being tried
+	word       int   // index of the word being tried; -1 before the first
+	dir        int   // index into directions
+	heads      []Pos // heads for words[word] in direction
+	head       int   // next head to try
+	placed     bool  // whether words[word] is on the grid at sequence
+	sequence   []Pos
+	viable     [][][]Pos // with mostConstrained, the acceptable heads of each word and direction
+	signature  uint64    // with a memo, the level's signature
+	node       int       // with a tree, the node of the placement the level builds on
+	placedNode int       // with a tree, the node of the current placement
+}
+
+// newSearch prepares a search placing words on an empty gridSize grid in
+// directions, the first word taking directions[0]. Nil directions are across
+// and down.
+func newSearch(words []string, gridSize int, directions []int, maxDepth, minCrossings int) *searchState {
+	if len(directions) == 0 {
+		directions = []int{HORIZONTAL, VERTICAL}
+	}
+	s := &searchState{
+		grid:          initGrid(gridSize),
+		cellDirection: initCellDir(gridSize),
+		index:         newGridIndex(),
+		book:          newCrossingBook(),
+		gridSize:      gridSize,
+		directions:    directions,
+		maxDepth:      maxDepth,
+		minCrossings:  minCrossings,
+	}
+	if len(words) > 0 {
+		s.stack = []searchFrame{{words: words, directions: directions[:1], direction: directions[0], word: -1}}
+	} else {
+		s.finished = true
+	}
+	return s
+}
+
+// run continues the search for at most steps placement attempts, or until it
+// finishes if steps <= 0, and reports whether it has finished.
+func (s *searchState) run(steps int) bool {
+	for n := 0; !s.finished && (steps <= 0 || n < steps); n++ {
+		s.step()
+	}
+	return s.finished
+}
+
+// step tries the next head of the frame on top of the stack.
+func (s *searchState) step() {
+	f := &s.stack[len(s.stack)-1]
+	if f.placed {
+		// the levels above could not complete this placement
+		s.unplace(f)
+	}
+	if f.word < 0 && s.mostConstrained {
+		s.orderWords(f)
+	}
+	for f.head >= len(f.heads) {
+		f.dir++
+		if f.word < 0 || f.dir >= len(f.directions) {
+			f.word, f.dir = f.word+1, 0
+		}
+		if f.word >= len(f.words) || len(f.directions) == 0 {
+			// every word and head failed: back to the level below
+			if s.memo != nil && len(s.stack) > 1 {
+				s.memo.add(f.signature)
+			}
+			s.backtracks++
+			if len(s.stack) > 1 {
+				s.tree.mark(f.node, NODE_DEAD_END)
 			}
+			s.stack = s.stack[:len(s.stack)-1]
+			s.finished = len(s.stack) == 0
+			return
+		}
+		f.direction = f.directions[f.dir]
+		if f.viable != nil {
+			f.heads, f.head = f.viable[f.word][f.dir], 0
 		} else {
-			allowedHeads = intersectingHead(word, direction, *cellDirection, *grid, gridSize)
+			f.heads, f.head = s.headsFor(f.words[f.word], f.direction), 0
+		}
+	}
+	head := f.heads[f.head]
+	f.head++
+
+	s.depth++
+	if s.depth > s.maxDepth {
+		for i := len(s.stack) - 1; i >= 0; i-- {
+			if s.stack[i].placed {
+				s.unplace(&s.stack[i])
+			}
 		}
+		s.stack, s.finished = nil, true
+		return
+	}
 
-		for _, head := range allowedHeads {
-			*depth++
-			if *depth > MAX_DEPTH {
-				return false, countIntersections()
+	word := f.words[f.word]
+	sequence := getSequence(head, f.direction, word)
+	reason := rejection(word, sequence, f.direction, s.grid, s.cellDirection, s.gridSize, s.index)
+	s.trace.record(word, reason)
+	if s.tree != nil {
+		state := NODE_TRIED
+		if reason != ACCEPTED {
+			state = NODE_REFUSED
+		}
+		f.placedNode = s.tree.add(f.node, fmt.Sprintf("%s %s @%d,%d", word, directionNames[f.direction], head.R+1, head.C+1), state, reason)
+	}
+	if reason != ACCEPTED {
+		return
+	}
+	addToGrid(word, sequence, f.direction, s.grid, s.cellDirection, s.index)
+	s.book.add(word, sequence)
+	s.replay.record(REPLAY_ADD, word, sequence, f.direction)
+	f.placed, f.sequence = true, sequence
+	if len(f.words) > 1 {
+		next := searchFrame{words: filterOut(f.words, word), directions: crossingDirections(s.directions, f.direction), word: -1, node: f.placedNode}
+		if s.memo != nil {
+			next.signature = s.memo.signature(next.words, directionAxes[f.direction], s.index)
+			if s.memo.has(next.signature) {
+				// failed before; the placement is undone on the next step
+				s.tree.mark(f.placedNode, NODE_DEAD_END)
+				return
 			}
+		}
+		s.stack = append(s.stack, next)
+		return
+	}
+	// the last word is placed; a failed check is undone on the next step
+	if s.book.satisfied(s.minCrossings) {
+		s.finished, s.accepted = true, true
+		for _, frame := range s.stack {
+			s.tree.mark(frame.placedNode, NODE_SOLUTION)
+		}
+	}
+}
 
-			sequence := getSequence(head, direction, word)
-			if isAcceptable(word, sequence, direction, *grid, *cellDirection, gridSize, *connections) {
-				addToGrid(word, sequence, direction, *grid, *cellDirection, *connections)
-				accept := false
-				if len(wordsList) > 1 {
-					// create new words list without current word
-					newWords := filterOut(wordsList, word)
-					ok, _ := createGrid(grid, newWords, gridSize, 1-direction, cellDirection, classification, depth, connections, MAX_DEPTH, reqIntersections)
-					accept = ok
-				} else {
-					accept = true
-				}
-				if accept {
-					// if intersections enough, mimic touch("lockfile") by simply noting success
-					if countIntersections() >= reqIntersections {
-						// record classification
-					}
-					// push classification for this direction
-					start := sequence[0]
-					(*classification)[direction] = append((*classification)[direction], Placement{Loc: gridSize*start.R + start.C, Word: word})
-					return true, countIntersections()
-				} else {
-					removeFromGrid(word, sequence, direction, *grid, *cellDirection, *connections)
+// headsFor lists the heads to try for word: every cell on an empty grid,
+// otherwise the heads crossing a placed word.
+func (s *searchState) headsFor(word string, direction int) []Pos {
+	if !s.index.empty() {
+		return intersectingHead(word, direction, s.cellDirection, s.index)
+	}
+	heads := make([]Pos, 0, s.gridSize*s.gridSize)
+	for r := 0; r < s.gridSize; r++ {
+		for c := 0; c < s.gridSize; c++ {
+			heads = append(heads, Pos{r, c})
+		}
+	}
+	return heads
+}
+
+// orderWords sorts f's words so that those with the fewest acceptable heads
+// come first (the most-constrained-variable heuristic), keeping the shuffled
+// order among equals, and keeps the heads for the frame to try.
+func (s *searchState) orderWords(f *searchFrame) {
+	type option struct {
+		word  string
+		heads [][]Pos // per direction
+		count int
+	}
+	options := make([]option, len(f.words))
+	for i, word := range f.words {
+		options[i].word = word
+		options[i].heads = make([][]Pos, len(f.directions))
+		for d, direction := range f.directions {
+			for _, head := range s.headsFor(word, direction) {
+				// only the refusals are traced here: the viable heads are
+				// tested again, and traced, when the search tries them
+				reason := rejection(word, getSequence(head, direction, word), direction, s.grid, s.cellDirection, s.gridSize, s.index)
+				if reason != ACCEPTED {
+					s.trace.record(word, reason)
+					continue
 				}
+				options[i].heads[d] = append(options[i].heads[d], head)
+				options[i].count++
 			}
 		}
 	}
+	sort.SliceStable(options, func(i, j int) bool { return options[i].count < options[j].count })
+	f.words = make([]string, len(options))
+	f.viable = make([][][]Pos, len(options))
+	for i, o := range options {
+		f.words[i], f.viable[i] = o.word, o.heads
+	}
+}
 
-	return false, countIntersections()
+// unplace takes f's word off the grid.
+func (s *searchState) unplace(f *searchFrame) {
+	word := f.words[f.word]
+	removeFromGrid(word, f.sequence, f.direction, s.grid, s.cellDirection, s.index)
+	s.book.remove(word, f.sequence)
+	s.replay.record(REPLAY_REMOVE, word, f.sequence, f.direction)
+	f.placed, f.sequence = false, nil
 }
 
-// --- helpers used in createGrid
-func allGridEmpty(grid map[Pos]rune) bool {
-	for _, v := range grid {
-		if v != '#' {
-			return false
-		}
+// classification returns the placements of an accepted search by direction,
+// with Placement.Order the position in the placement sequence.
+func (s *searchState) classification() map[int][]Placement {
+	classification := map[int][]Placement{}
+	for _, dir := range s.directions {
+		classification[dir] = []Placement{}
 	}
-	return true
+	if !s.accepted {
+		return classification
+	}
+	for i, f := range s.stack {
+		start := f.sequence[0]
+		classification[f.direction] = append(classification[f.direction], Placement{Loc: s.gridSize*start.R + start.C, Word: f.words[f.word], Order: i})
+	}
+	return classification
 }
 
+// --- helpers used in the search
 func filterOut(words []string, target string) []string {
 	out := make([]string, 0, len(words)-1)
 	for _, w := range words {
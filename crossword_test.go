@@ -0,0 +1,141 @@
+// file: crossword_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// testPlacement is a word put on a test grid before the placement checked.
+type testPlacement struct {
+	head Pos
+	dir  int
+	word string
+}
+
+// testGrid returns a size x size grid holding placed.
+func testGrid(size int, placed []testPlacement) (map[Pos]rune, map[Pos]string, *gridIndex) {
+	grid, cellDir, index := initGrid(size), initCellDir(size), newGridIndex()
+	for _, p := range placed {
+		addToGrid(p.word, getSequence(p.head, p.dir, p.word), p.dir, grid, cellDir, index)
+	}
+	return grid, cellDir, index
+}
+
+func TestRejection(t *testing.T) {
+	// CAT across on row 3, from column 2 of a 6x6 grid
+	cat := []testPlacement{{Pos{2, 1}, HORIZONTAL, "CAT"}}
+	// and ACE down through its A
+	catAce := append(cat, testPlacement{Pos{2, 2}, VERTICAL, "ACE"})
+	tests := []struct {
+		name   string
+		placed []testPlacement
+		head   Pos
+		dir    int
+		word   string
+		want   int
+	}{
+		{"empty grid", nil, Pos{0, 0}, HORIZONTAL, "CAT", ACCEPTED},
+		{"empty grid, last cell", nil, Pos{5, 3}, HORIZONTAL, "CAT", ACCEPTED},
+		{"runs off the right", nil, Pos{0, 4}, HORIZONTAL, "CAT", REJECT_BOUNDARY},
+		{"runs off the bottom", nil, Pos{4, 0}, VERTICAL, "CAT", REJECT_BOUNDARY},
+		{"starts above the grid", nil, Pos{-1, 0}, VERTICAL, "CAT", REJECT_BOUNDARY},
+		{"crosses a matching letter", cat, Pos{2, 2}, VERTICAL, "ACE", ACCEPTED},
+		{"crosses through its middle", cat, Pos{1, 3}, VERTICAL, "STY", ACCEPTED},
+		{"crosses a different letter", cat, Pos{1, 2}, VERTICAL, "BOX", REJECT_MISMATCH},
+		{"lies on the same word", cat, Pos{2, 1}, HORIZONTAL, "CAT", REJECT_DIRECTION},
+		{"overlaps on the same axis", cat, Pos{2, 3}, HORIZONTAL, "TO", REJECT_ADJACENT},
+		{"continues it end-on", cat, Pos{2, 4}, HORIZONTAL, "GO", REJECT_ADJACENT},
+		{"sits beside its first letter", cat, Pos{2, 0}, VERTICAL, "X", REJECT_ADJACENT},
+		{"lies alongside", cat, Pos{3, 1}, HORIZONTAL, "DOG", REJECT_ADJACENT},
+		{"touches a side", cat, Pos{1, 0}, VERTICAL, "OXO", REJECT_ADJACENT},
+		{"touches only a corner", cat, Pos{3, 4}, VERTICAL, "OX", ACCEPTED},
+		{"runs beside a crossing word", catAce, Pos{3, 3}, VERTICAL, "EE", REJECT_ADJACENT},
+		{"crosses a crossing", catAce, Pos{2, 2}, VERTICAL, "ACE", REJECT_DIRECTION},
+		{"crosses the other word", catAce, Pos{4, 1}, HORIZONTAL, "TEN", ACCEPTED},
+		{"two apart", cat, Pos{4, 1}, HORIZONTAL, "DOG", ACCEPTED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid, cellDir, index := testGrid(6, tt.placed)
+			seq := getSequence(tt.head, tt.dir, tt.word)
+			if got := rejection(tt.word, seq, tt.dir, grid, cellDir, 6, index); got != tt.want {
+				t.Errorf("rejection = %d, want %d", got, tt.want)
+			}
+			if got := isAcceptable(tt.word, seq, tt.dir, grid, cellDir, 6, index); got != (tt.want == ACCEPTED) {
+				t.Errorf("isAcceptable = %v, want %v", got, tt.want == ACCEPTED)
+			}
+		})
+	}
+}
+
+func TestSearchRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		words        []string
+		size         int
+		maxDepth     int
+		minCrossings int
+		accepted     bool
+		grid         string // the layout found; "" to only check the rules
+	}{
+		{"one word", []string{"CAT"}, 4, 1000, 0, true, "CAT#\n####\n####\n####\n"},
+		{"two crossing words", []string{"CAT", "ACE"}, 4, 1000, 0, true, "CAT#\n#C##\n#E##\n####\n"},
+		{"a chain", []string{"CATS", "ACE", "SEA", "TEN"}, 6, 100000, 0, true, ""},
+		{"no shared letters", []string{"ABC", "XYZ"}, 4, 100000, 0, false, ""},
+		{"word longer than the grid", []string{"CATS"}, 3, 100000, 0, false, ""},
+		{"depth budget spent", []string{"ABC", "XYZ"}, 4, 3, 0, false, ""},
+		{"every word crossed twice", []string{"CAT", "ACT", "TAT"}, 5, 100000, 2, false, ""},
+		{"every word crossed once", []string{"CAT", "ACE", "TEA"}, 5, 100000, 1, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSearch(tt.words, tt.size, nil, tt.maxDepth, tt.minCrossings)
+			if !s.run(0) {
+				t.Fatal("run(0) returned before the search finished")
+			}
+			if s.accepted != tt.accepted {
+				t.Fatalf("accepted = %v, want %v", s.accepted, tt.accepted)
+			}
+			if !s.accepted {
+				// a refused search takes every word back off the grid
+				for loc, r := range s.grid {
+					if r != '#' {
+						t.Fatalf("cell %v still holds %c", loc, r)
+					}
+				}
+				return
+			}
+			p := newPuzzle(tt.size, s.grid, s.classification(), nil)
+			if got := len(p.entries()); got != len(tt.words) {
+				t.Errorf("%d entries, want %d", got, len(tt.words))
+			}
+			for _, v := range Validate(p, tt.words) {
+				t.Errorf("layout breaks the rules: %v", v)
+			}
+			if tt.grid != "" {
+				if got := strings.ReplaceAll(strings.ReplaceAll(p.String(), " ", ""), ".", "#"); got != tt.grid {
+					t.Errorf("grid\n%s\nwant\n%s", got, tt.grid)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchRunResumes(t *testing.T) {
+	words := []string{"CATS", "ACE", "SEA", "TEN", "NET"}
+	whole := newSearch(words, 6, nil, 100000, 0)
+	whole.run(0)
+
+	paused := newSearch(words, 6, nil, 100000, 0)
+	for !paused.run(1) {
+	}
+	if paused.accepted != whole.accepted || paused.depth != whole.depth {
+		t.Fatalf("stepped search: accepted %v after %d placements, want %v after %d", paused.accepted, paused.depth, whole.accepted, whole.depth)
+	}
+	a := newPuzzle(6, whole.grid, whole.classification(), nil)
+	b := newPuzzle(6, paused.grid, paused.classification(), nil)
+	if a.String() != b.String() {
+		t.Errorf("stepped search found\n%s\nwant\n%s", b, a)
+	}
+}
@@ -0,0 +1,75 @@
+// file: dict.go
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// dictionary indexes a word list for membership tests and pattern lookups.
+type dictionary struct {
+	list  []string // in dictionary order, without duplicates
+	words map[string]bool
+	index *trie // built on the first match, see trie.go
+}
+
+// newDictionary builds a dictionary from words, dropping duplicates.
+func newDictionary(words []string) *dictionary {
+	d := &dictionary{words: make(map[string]bool)}
+	for _, w := range words {
+		if d.words[w] {
+			continue
+		}
+		d.words[w] = true
+		d.list = append(d.list, w)
+	}
+	return d
+}
+
+// has reports whether word is in the dictionary.
+func (d *dictionary) has(word string) bool {
+	return d.words[word]
+}
+
+// match returns the words fitting pattern, where '?' stands for any letter,
+// in dictionary order.
+func (d *dictionary) match(pattern string) []string {
+	if d.index == nil {
+		d.index = newTrie(d.list)
+	}
+	var found []int
+	d.index.match([]rune(pattern), func(word int) { found = append(found, word) })
+	sort.Ints(found)
+	out := make([]string, len(found))
+	for i, word := range found {
+		out[i] = d.list[word]
+	}
+	return out
+}
+
+// anagrams returns the words using exactly the letters of letters, where
+// '?' is a blank standing for any letter, in dictionary order.
+func (d *dictionary) anagrams(letters string) []string {
+	var out []string
+	for _, w := range d.match(strings.Repeat("?", utf8.RuneCountInString(letters))) {
+		left := []rune(letters)
+		ok := true
+		for _, r := range w {
+			i := slices.Index(left, r)
+			if i < 0 {
+				i = slices.Index(left, '?')
+			}
+			if i < 0 {
+				ok = false
+				break
+			}
+			left = slices.Delete(left, i, i+1)
+		}
+		if ok {
+			out = append(out, w)
+		}
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+// file: difficulty.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The difficulty estimate combines four measures, each scaled to 0 (easy) to
+// 1 (hard): how rare the answers are in a frequency list, how long they are,
+// how few other words each crosses, and how few letters are checked by a
+// crossing. Without a frequency list the rarity measure is left out.
+
+// Weights of the measures in the score; they need not sum to 1.
+const (
+	DIFFICULTY_OBSCURITY_WEIGHT = 0.4
+	DIFFICULTY_LENGTH_WEIGHT    = 0.2
+	DIFFICULTY_CROSSING_WEIGHT  = 0.2
+	DIFFICULTY_CHECKED_WEIGHT   = 0.2
+
+	// scores below these fall in the easy and medium buckets
+	DIFFICULTY_EASY_BELOW   = 35
+	DIFFICULTY_MEDIUM_BELOW = 65
+)
+
+// Difficulty is the estimated solving difficulty of a puzzle.
+type Difficulty struct {
+	Score     float64 `json:"score"`               // 0 (easiest) to 100
+	Level     string  `json:"level"`               // "easy", "medium" or "hard"
+	Obscurity float64 `json:"obscurity,omitempty"` // mean frequency rank of the answers, 0 to 1; 0 without a list
+	Length    float64 `json:"length"`              // mean answer length, 3 letters = 0, 10 or more = 1
+	Crossing  float64 `json:"crossing"`            // 1 - crossings per answer / 3
+	Unchecked float64 `json:"unchecked"`           // share of letter cells in a single answer
+}
+
+// frequencies ranks words from most to least common.
+type frequencies struct {
+	rank map[string]int
+}
+
+// readFrequencies loads a frequency list: one word per line, most common
+// first, or "word count" lines in any order. Blank lines and '#' comments
+// are skipped.
+func readFrequencies(path string) (*frequencies, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	type counted struct {
+		word  string
+		count float64
+	}
+	var words []counted
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		w := counted{word: strings.ToUpper(fields[0]), count: -float64(len(words))}
+		if len(fields) > 1 {
+			if n, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				w.count = n
+			}
+		}
+		words = append(words, w)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%s: no words", path)
+	}
+	sort.SliceStable(words, func(i, j int) bool { return words[i].count > words[j].count })
+	freq := &frequencies{rank: make(map[string]int)}
+	for i, w := range words {
+		if _, ok := freq.rank[w.word]; !ok {
+			freq.rank[w.word] = i
+		}
+	}
+	return freq, nil
+}
+
+// obscurity returns word's rank scaled to 0 (most common) to 1; words not
+// in the list count as 1.
+func (f *frequencies) obscurity(word string) float64 {
+	rank, ok := f.rank[strings.ToUpper(word)]
+	if !ok {
+		return 1
+	}
+	return float64(rank) / float64(len(f.rank))
+}
+
+// Rate estimates p's difficulty, using freq for the rarity of the answers if
+// it is not nil, and keeps the estimate with the puzzle for the writers.
+func (p *Puzzle) Rate(freq *frequencies) Difficulty {
+	entries := p.entries()
+	var d Difficulty
+	if len(entries) == 0 {
+		p.difficulty = &d
+		return d
+	}
+
+	letters, length, crossings, obscurity := 0, 0.0, 0.0, 0.0
+	for _, e := range entries {
+		length += float64(len([]rune(e.Word)))
+		crossings += float64(p.Crossings(e))
+		if freq != nil {
+			obscurity += freq.obscurity(e.Word)
+		}
+	}
+	n := float64(len(entries))
+	for _, ch := range p.grid {
+		if ch != '#' {
+			letters++
+		}
+	}
+	d.Length = min(max((length/n-3)/7, 0), 1)
+	d.Crossing = 1 - min(crossings/n/3, 1)
+	d.Unchecked = 1 - float64(len(p.crossings()))/float64(letters)
+
+	score := DIFFICULTY_LENGTH_WEIGHT*d.Length + DIFFICULTY_CROSSING_WEIGHT*d.Crossing + DIFFICULTY_CHECKED_WEIGHT*d.Unchecked
+	weights := DIFFICULTY_LENGTH_WEIGHT + DIFFICULTY_CROSSING_WEIGHT + DIFFICULTY_CHECKED_WEIGHT
+	if freq != nil {
+		d.Obscurity = obscurity / n
+		score += DIFFICULTY_OBSCURITY_WEIGHT * d.Obscurity
+		weights += DIFFICULTY_OBSCURITY_WEIGHT
+	}
+	d.Score = 100 * score / weights
+	switch {
+	case d.Score < DIFFICULTY_EASY_BELOW:
+		d.Level = "easy"
+	case d.Score < DIFFICULTY_MEDIUM_BELOW:
+		d.Level = "medium"
+	default:
+		d.Level = "hard"
+	}
+	p.difficulty = &d
+	return d
+}
@@ -0,0 +1,216 @@
+// file: doctor.go
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// doctorCheck is one line of the doctor report. fix is printed under
+// warnings and failures.
+type doctorCheck struct {
+	status string // "ok", "warn" or "FAIL"
+	msg    string
+	fix    string
+}
+
+// runDoctor validates the inputs a run with the same flags would use and
+// prints what is wrong and how to fix it. Returns the process exit code.
+func runDoctor(c *cli, w io.Writer) int {
+	var checks []doctorCheck
+	add := func(status, msg, fix string) {
+		checks = append(checks, doctorCheck{status, msg, fix})
+	}
+
+	if _, err := newLogger(c.logFormat, c.quiet, c.verbose); err != nil {
+		add("FAIL", err.Error(), "use -log-format text or -log-format json")
+	}
+	switch c.mode {
+	case "crossword", "codeword", "krisskross", "wordsearch":
+	default:
+		add("FAIL", fmt.Sprintf("unknown -mode %q", c.mode), "use -mode crossword, codeword, krisskross or wordsearch")
+	}
+	if _, err := c.grid(); err != nil {
+		add("FAIL", err.Error(), "use -format "+strings.Join(gridFormats(), " or -format "))
+	}
+	if err := checkRestart(c.restart); err != nil {
+		add("FAIL", err.Error(), "use -restart "+strings.Join(restartNames(), " or -restart "))
+	}
+	if _, err := c.placementDirections(); err != nil {
+		add("FAIL", "-directions: "+err.Error(), "use names from "+strings.Join(directionNames[:], ", "))
+	}
+	if err := c.exporter(); err != nil {
+		add("FAIL", err.Error(), "use -export "+strings.Join(exportFormats(), " or -export "))
+	}
+	if c.count < 1 {
+		add("FAIL", fmt.Sprintf("-count is %d", c.count), "ask for at least one puzzle")
+	}
+	if c.top < 1 {
+		add("FAIL", fmt.Sprintf("-top is %d", c.top), "ask for at least one layout")
+	} else if c.top > 1 && (c.count > 1 || c.autoSize) {
+		add("FAIL", "-top is combined with -count or -auto-size", "drop -count and -auto-size; -top writes its layouts as the batch")
+	}
+
+	pools, err := c.wordPools()
+	if err != nil {
+		add("FAIL", "word list: "+err.Error(), "check the -wordfile paths; files need one word per line")
+	} else {
+		sources := []string{"the built-in list"}
+		if c.wordFiles != "" {
+			sources = strings.Split(c.wordFiles, ",")
+		}
+		for i, pool := range pools {
+			add("ok", fmt.Sprintf("%d words from %s", len(pool), sources[i]), "")
+			checks = append(checks, checkWords(pool, c)...)
+		}
+	}
+
+	if _, err := c.accidental(); err != nil {
+		add("FAIL", "accidental: "+err.Error(), "check the -accidental path")
+	}
+	if block, err := c.blocked(); err != nil {
+		add("FAIL", "blocklist: "+err.Error(), "check the -blocklist path")
+	} else if block != nil && pools != nil {
+		for _, pool := range pools {
+			for _, w := range pool {
+				if onBlocklist(w, block) {
+					add("warn", w+" is on the blocklist and will be dropped", "remove it from the word list")
+				}
+			}
+		}
+	}
+	if _, err := c.frequencies(); err != nil {
+		add("FAIL", "freq: "+err.Error(), "check the -freq path")
+	}
+
+	if clues, err := c.clues(); err != nil {
+		add("FAIL", "clues: "+err.Error(), "check the -clues paths")
+	} else if clues != nil && pools != nil && c.llmEndpoint == "" {
+		missing := 0
+		for _, pool := range pools {
+			for _, w := range pool {
+				if _, err := clues.Clue(w); err != nil {
+					missing++
+				}
+			}
+		}
+		if missing > 0 {
+			add("warn", fmt.Sprintf("%d words have no clue in %s", missing, c.clueFiles), "add WORD<tab>clue lines for them to a clue file")
+		} else {
+			add("ok", "every word has a clue", "")
+		}
+	}
+
+	if c.llmEndpoint != "" {
+		if os.Getenv(c.llmKeyEnv) == "" {
+			add("warn", c.llmKeyEnv+" is not set; clue requests are sent without an API key", "export "+c.llmKeyEnv+" unless the endpoint needs no key")
+		} else {
+			add("ok", "clue drafts from "+c.llmEndpoint+" with model "+c.llmModel, "")
+		}
+	}
+
+	if c.imports != "" {
+		if p, err := readPuzzleFile(c.imports); err != nil {
+			add("FAIL", "import: "+err.Error(), "check the -import path and that the file is a complete puzzle")
+		} else {
+			add("ok", fmt.Sprintf("%s holds a %dx%d puzzle with %d entries", c.imports, p.Rows, p.Cols, len(p.entries())), "")
+			for _, v := range Validate(p, nil) {
+				add("warn", "import: "+v.String(), "fix the grid before extending or exporting it")
+			}
+		}
+	}
+
+	if c.out != "" {
+		dir := filepath.Dir(c.out)
+		if f, err := os.CreateTemp(dir, ".doctor-*"); err != nil {
+			add("FAIL", "output directory not writable: "+err.Error(), "create "+dir+" or point -out somewhere writable")
+		} else {
+			f.Close()
+			os.Remove(f.Name())
+			add("ok", "output directory "+dir+" is writable", "")
+		}
+	}
+
+	failed := false
+	for _, ch := range checks {
+		fmt.Fprintf(w, "[%s] %s\n", ch.status, ch.msg)
+		if ch.fix != "" && ch.status != "ok" {
+			fmt.Fprintf(w, "       fix: %s\n", ch.fix)
+		}
+		failed = failed || ch.status == "FAIL"
+	}
+	if failed {
+		return EXIT_FAILURE
+	}
+	return EXIT_OK
+}
+
+// checkWords looks for words the generator cannot place or will treat oddly.
+func checkWords(words []string, c *cli) []doctorCheck {
+	var checks []doctorCheck
+	counts := make(map[string]int)
+	letters := 0
+	lang, _ := lookupLanguage(c.lang) // checked by parseCLI
+	for _, w := range words {
+		placed := gridForm(w, c.folds())
+		counts[placed]++
+		n := len([]rune(placed))
+		letters += n
+
+		if n > c.gridSize && !c.autoSize {
+			checks = append(checks, doctorCheck{"FAIL", fmt.Sprintf("%s has %d letters but the grid is %dx%d", w, n, c.gridSize, c.gridSize),
+				"increase the grid size or drop the word"})
+		}
+		for _, r := range placed {
+			if !unicode.IsLetter(r) {
+				checks = append(checks, doctorCheck{"warn", fmt.Sprintf("%s contains %q, which will take up a cell", w, r),
+					"remove punctuation other than spaces, hyphens and apostrophes from answers"})
+				break
+			}
+			if !lang.hasLetter(unicode.ToUpper(r)) {
+				check := doctorCheck{"warn", fmt.Sprintf("%s contains %q, which is not in the %s alphabet", w, r, lang.name),
+					"check the -lang profile or respell the word"}
+				// only accents the profile folds are helped by folding
+				if _, folded := (Options{FoldAccents: true, Language: c.lang}).folds()[unicode.ToUpper(r)]; folded && !c.foldAccents {
+					check.fix = "enable accent folding if accented letters should cross their plain forms"
+				}
+				checks = append(checks, check)
+				break
+			}
+		}
+	}
+	for w, n := range counts {
+		if n > 1 {
+			checks = append(checks, doctorCheck{"warn", fmt.Sprintf("%s is listed %d times and will be placed once", w, n),
+				"remove the duplicates"})
+		}
+	}
+	// every intersection uses one letter of each of two words
+	if c.reqIntersections > letters/2 {
+		checks = append(checks, doctorCheck{"FAIL", fmt.Sprintf("%d intersections required but the words have only %d letters", c.reqIntersections, letters),
+			"lower the required intersections or add words"})
+	}
+	// a word crosses each perpendicular word at most once
+	if c.minCrossings > 0 && len(counts) <= c.minCrossings {
+		checks = append(checks, doctorCheck{"FAIL", fmt.Sprintf("-min-crossings %d needs more than %d distinct words", c.minCrossings, c.minCrossings),
+			"lower -min-crossings or add words"})
+	}
+	// a cell holds at most one letter, so the words bound the fill
+	if c.minDensity > 100 {
+		checks = append(checks, doctorCheck{"FAIL", fmt.Sprintf("-density %g is above 100%%", c.minDensity),
+			"give the density as a percentage between 0 and 100"})
+	} else if need := c.minDensity / 100 * float64(c.gridSize*c.gridSize); !c.autoSize && letters < int(need+0.5) {
+		checks = append(checks, doctorCheck{"FAIL", fmt.Sprintf("-density %g%% needs %.0f filled cells but the words have only %d letters", c.minDensity, need, letters),
+			"lower -density, shrink the grid or add words"})
+	}
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{"ok", "all words fit the grid", ""})
+	}
+	return checks
+}
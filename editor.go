@@ -0,0 +1,122 @@
+// file: editor.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Manual editing of a finished puzzle, for frontends that let the user move
+// words around. Every edit is checked with the generator's own isAcceptable
+// rules and recorded, so it can be undone and redone.
+
+// edit is one recorded change: an entry placed or removed.
+type edit struct {
+	place bool
+	entry Entry
+}
+
+// PlaceWord puts word on the grid with its first letter at (row, col), 0-based,
+// in direction dir (HORIZONTAL or VERTICAL). It fails if the word is already
+// placed, leaves the grid, or breaks the placement rules. A phrase is placed
+// without its spaces and hyphens.
+func (p *Puzzle) PlaceWord(word string, row, col, dir int) error {
+	if dir != HORIZONTAL && dir != VERTICAL {
+		return fmt.Errorf("direction must be across or down")
+	}
+	placed := strings.ToUpper(gridForm(word, nil))
+	if placed == "" {
+		return fmt.Errorf("%q has no letters to place", word)
+	}
+	if _, ok := p.Find(placed); ok {
+		return fmt.Errorf("%s is already placed", word)
+	}
+	seq := getSequence(Pos{row, col}, dir, placed)
+	for _, loc := range []Pos{seq[0], seq[len(seq)-1]} {
+		if loc.R < 0 || loc.R >= p.Rows || loc.C < 0 || loc.C >= p.Cols {
+			return fmt.Errorf("%s does not fit in the grid at (%d, %d)", word, row+1, col+1)
+		}
+	}
+
+	size := max(p.Rows, p.Cols)
+	grid := initGrid(size)
+	cellDir := initCellDir(size)
+	var existing []tilePlacement
+	order := 0
+	for _, e := range p.entries() {
+		existing = append(existing, tilePlacement{Pos{e.Row, e.Col}, e.Direction, e.Word})
+		order = max(order, e.Order+1)
+	}
+	index := newGridIndex()
+	seedTile(Pos{}, size, existing, grid, cellDir, index)
+	if !isAcceptable(placed, seq, dir, grid, cellDir, size, index) {
+		return fmt.Errorf("%s cannot be placed at (%d, %d)", word, row+1, col+1)
+	}
+
+	p.record(edit{place: true, entry: Entry{Row: row, Col: col, Direction: dir, Word: placed, Display: word, Order: order}})
+	return nil
+}
+
+// RemoveWord takes word (matched as by Find) off the grid.
+func (p *Puzzle) RemoveWord(word string) error {
+	e, ok := p.Find(word)
+	if !ok {
+		return fmt.Errorf("%s is not in the puzzle", word)
+	}
+	p.record(edit{place: false, entry: e})
+	return nil
+}
+
+// Undo reverts the last edit, reporting whether there was one.
+func (p *Puzzle) Undo() bool {
+	if len(p.undo) == 0 {
+		return false
+	}
+	last := p.undo[len(p.undo)-1]
+	p.undo = p.undo[:len(p.undo)-1]
+	p.apply(edit{place: !last.place, entry: last.entry})
+	p.redo = append(p.redo, last)
+	return true
+}
+
+// Redo applies the last undone edit again, reporting whether there was one.
+func (p *Puzzle) Redo() bool {
+	if len(p.redo) == 0 {
+		return false
+	}
+	next := p.redo[len(p.redo)-1]
+	p.redo = p.redo[:len(p.redo)-1]
+	p.apply(next)
+	p.undo = append(p.undo, next)
+	return true
+}
+
+// record applies a new edit; it starts a new history branch, so the redo
+// stack is dropped.
+func (p *Puzzle) record(e edit) {
+	p.apply(e)
+	p.undo = append(p.undo, e)
+	p.redo = nil
+}
+
+// apply adds or removes e's entry, then rebuilds the grid from the entries
+// and renumbers them.
+func (p *Puzzle) apply(e edit) {
+	var entries []Entry
+	for _, old := range p.entries() {
+		if e.place || old.Row != e.entry.Row || old.Col != e.entry.Col || old.Direction != e.entry.Direction {
+			entries = append(entries, old)
+		}
+	}
+	if e.place {
+		entries = append(entries, e.entry)
+	}
+	p.grid = make(map[Pos]rune)
+	for _, entry := range entries {
+		runes := []rune(entry.Word)
+		for i, loc := range getSequence(Pos{entry.Row, entry.Col}, entry.Direction, entry.Word) {
+			p.grid[loc] = runes[i]
+		}
+	}
+	p.setEntries(entries)
+}
@@ -0,0 +1,16 @@
+// file: editor_test.go
+package main
+
+import "testing"
+
+func TestPlaceWordWithoutLetters(t *testing.T) {
+	p, err := Generate([]string{"CAT", "ACE"}, WithSize(8), WithIntersections(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, word := range []string{"", "-", " ", "'"} {
+		if err := p.PlaceWord(word, 0, 0, HORIZONTAL); err == nil {
+			t.Errorf("PlaceWord(%q) placed a word with no letters", word)
+		}
+	}
+}
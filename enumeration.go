@@ -0,0 +1,99 @@
+// file: enumeration.go
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Answers may be phrases ("BLOOD BRAIN BARRIER", "GOOD-LOOKING"). The grid
+// holds only their letters; the word lengths survive as the enumeration
+// printed after the clue, "(5,5,7)" or "(4-7)", as in cryptic and quick
+// crosswords. Apostrophes are dropped without splitting the word.
+
+// gridForm returns the letters of an input answer as they go into the grid:
+// accents folded by folds (nil for none), and spaces, hyphens and
+// apostrophes removed.
+func gridForm(word string, folds map[rune]string) string {
+	if folds != nil {
+		word = foldWord(word, folds)
+	}
+	return strings.Map(func(r rune) rune {
+		if isWordBreak(r) || r == '\'' || r == '’' {
+			return -1
+		}
+		return r
+	}, word)
+}
+
+// isWordBreak reports whether r separates the words of a phrase.
+func isWordBreak(r rune) bool {
+	return unicode.IsSpace(r) || r == '-'
+}
+
+// enumeration returns the enumeration of a multi-word answer, e.g. "5,5,7",
+// or "" for a single word. The words of display are counted in the cells of
+// placed, its grid form, so a folded ß or Æ counts as the two letters it
+// fills.
+func enumeration(display, placed string) string {
+	var b strings.Builder
+	cells := []rune(strings.ToUpper(placed))
+	n, parts, at := 0, 0, 0
+	flush := func(sep rune) {
+		if n == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%d", n)
+		parts++
+		n = 0
+		if sep != 0 {
+			b.WriteRune(sep)
+		}
+	}
+	for _, r := range strings.TrimSpace(display) {
+		switch {
+		case unicode.IsSpace(r):
+			flush(',')
+		case r == '-':
+			flush('-')
+		case r == '\'' || r == '’':
+		default:
+			width := cellWidth(r, cells[min(at, len(cells)):])
+			n += width
+			at += width
+		}
+	}
+	flush(0)
+	if parts < 2 {
+		return ""
+	}
+	return strings.TrimRight(b.String(), ",-")
+}
+
+// cellWidth returns how many of the grid cells rest starts with hold the
+// answer letter r: one, or two for a ß or ligature folded on placement.
+func cellWidth(r rune, rest []rune) int {
+	upper := unicode.ToUpper(r)
+	if len(rest) > 0 && rest[0] == upper {
+		return 1
+	}
+	if folded, ok := accentFolds[upper]; ok && strings.HasPrefix(string(rest), folded) {
+		return len([]rune(folded))
+	}
+	return 1
+}
+
+// Enumeration returns the enumeration of e's answer, or "" for one word.
+func (e Entry) Enumeration() string {
+	return enumeration(e.Display, e.Word)
+}
+
+// lengths returns the enumeration of e, or its length for a single word,
+// for formats that always give one.
+func (e Entry) lengths() string {
+	if enum := e.Enumeration(); enum != "" {
+		return enum
+	}
+	return fmt.Sprint(len([]rune(e.Word)))
+}
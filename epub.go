@@ -0,0 +1,215 @@
+// file: epub.go
+package main
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// An EPUB is a zip of XHTML pages with a package file listing them. The
+// puzzle pack has a page per puzzle, each linking to its solution page and
+// back, and a table of contents e-readers show in their navigation menu.
+// Grids are HTML tables styled by one stylesheet, so they reflow to the
+// reader's screen.
+
+// epubStyle is the stylesheet of every page.
+const epubStyle = `table.grid { border-collapse: collapse; margin: 1em auto; }
+table.grid td { width: 1.8em; height: 1.8em; border: 1px solid #000; padding: 0;
+  position: relative; text-align: center; vertical-align: middle; font-size: 1.1em; }
+table.grid td.block { background: #000; }
+table.grid td.shade { background: ` + SHADE_COLOUR + `; }
+table.grid td.circle span.letter { border: 1px solid #000; border-radius: 50%; display: inline-block; width: 1.4em; }
+table.grid td.bar-right { border-right-width: 3px; }
+table.grid td.bar-below { border-bottom-width: 3px; }
+table.grid sup { position: absolute; top: 1px; left: 2px; font-size: 0.5em; }
+h1, h2 { text-align: center; }
+ol.clues { list-style: none; padding-left: 0; }
+`
+
+// writeEPUB writes the puzzles as an EPUB 3 puzzle pack titled title.
+func writeEPUB(w io.Writer, puzzles []*Puzzle, title string) error {
+	z := zip.NewWriter(w)
+	now := time.Now().UTC()
+	// the mimetype comes first and uncompressed, so readers can sniff it
+	mt, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store, Modified: now})
+	if err != nil {
+		return err
+	}
+	io.WriteString(mt, "application/epub+zip")
+
+	files := map[string]string{
+		"META-INF/container.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>
+`,
+		"OEBPS/style.css": epubStyle,
+	}
+	order := []string{"META-INF/container.xml", "OEBPS/style.css", "OEBPS/nav.xhtml"}
+	var manifest, spine, toc strings.Builder
+	for i, p := range puzzles {
+		puzzle, solution := fmt.Sprintf("puzzle-%d.xhtml", i+1), fmt.Sprintf("solution-%d.xhtml", i+1)
+		name := epubPuzzleName(p, i)
+		files["OEBPS/"+puzzle] = epubPage(name, epubPuzzleBody(p, name, solution))
+		files["OEBPS/"+solution] = epubPage(name+": solution", epubSolutionBody(p, name, puzzle))
+		order = append(order, "OEBPS/"+puzzle, "OEBPS/"+solution)
+		fmt.Fprintf(&manifest, "    <item id=\"p%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, puzzle)
+		fmt.Fprintf(&manifest, "    <item id=\"s%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, solution)
+		fmt.Fprintf(&spine, "    <itemref idref=\"p%d\"/>\n", i+1)
+		fmt.Fprintf(&toc, "      <li><a href=\"%s\">%s</a> (<a href=\"%s\">solution</a>)</li>\n", puzzle, html.EscapeString(name), solution)
+	}
+	// the solutions follow all the puzzles when read straight through
+	for i := range puzzles {
+		fmt.Fprintf(&spine, "    <itemref idref=\"s%d\"/>\n", i+1)
+	}
+	files["OEBPS/nav.xhtml"] = epubPage(title,
+		fmt.Sprintf("<h1>%s</h1>\n<nav epub:type=\"toc\" id=\"toc\">\n  <ol>\n%s  </ol>\n</nav>\n", html.EscapeString(title), toc.String()))
+
+	var id [16]byte
+	rand.Read(id[:])
+	id[6], id[8] = id[6]&0x0f|0x40, id[8]&0x3f|0x80 // version 4 UUID
+	order = append(order, "OEBPS/content.opf")
+	files["OEBPS/content.opf"] = fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="id">urn:uuid:%x-%x-%x-%x-%x</dc:identifier>
+    <dc:title>%s</dc:title>
+    <dc:language>en</dc:language>
+    <meta property="dcterms:modified">%s</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+%s  </manifest>
+  <spine>
+    <itemref idref="nav"/>
+%s  </spine>
+</package>
+`, id[0:4], id[4:6], id[6:8], id[8:10], id[10:], html.EscapeString(title), now.Format("2006-01-02T15:04:05Z"), manifest.String(), spine.String())
+
+	for _, name := range order {
+		f, err := z.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, files[name]); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// epubPuzzleName is the heading of the i-th puzzle (0-based) of a pack.
+func epubPuzzleName(p *Puzzle, i int) string {
+	if p.Meta.Title != "" {
+		return fmt.Sprintf("%s %d", p.Meta.Title, i+1)
+	}
+	return fmt.Sprintf("Puzzle %d", i+1)
+}
+
+// epubPage wraps body in an XHTML page.
+func epubPage(title, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>%s</title><link rel="stylesheet" type="text/css" href="style.css"/></head>
+<body>
+%s</body>
+</html>
+`, html.EscapeString(title), body)
+}
+
+// epubPuzzleBody is the page of a puzzle: the empty grid, the clues and a
+// link to the solution.
+func epubPuzzleBody(p *Puzzle, name, solution string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(name))
+	if p.Meta.Author != "" {
+		fmt.Fprintf(&b, "<p style=\"text-align: center\">by %s</p>\n", html.EscapeString(p.Meta.Author))
+	}
+	if p.Meta.Notes != "" {
+		fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(p.Meta.Notes))
+	}
+	b.WriteString(epubGrid(p, true))
+	for _, list := range []struct {
+		title   string
+		entries []Entry
+	}{{"Across", p.AcrossEntries()}, {"Down", p.DownEntries()}} {
+		fmt.Fprintf(&b, "<h2>%s</h2>\n<ol class=\"clues\">\n", list.title)
+		for _, e := range list.entries {
+			clue := p.ClueText(e)
+			if e.Clue == "" {
+				clue = "(" + e.lengths() + ")"
+			}
+			fmt.Fprintf(&b, "  <li><b>%d</b> %s</li>\n", e.Number, html.EscapeString(clue))
+		}
+		b.WriteString("</ol>\n")
+	}
+	fmt.Fprintf(&b, "<p><a href=\"%s\">Solution</a></p>\n", solution)
+	if p.Meta.Copyright != "" {
+		fmt.Fprintf(&b, "<p><small>© %s</small></p>\n", html.EscapeString(strings.TrimPrefix(p.Meta.Copyright, "© ")))
+	}
+	return b.String()
+}
+
+// epubSolutionBody is the solution page of a puzzle, linking back to it.
+func epubSolutionBody(p *Puzzle, name, puzzle string) string {
+	return fmt.Sprintf("<h1>%s: solution</h1>\n%s<p><a href=\"%s\">Back to the puzzle</a></p>\n",
+		html.EscapeString(name), epubGrid(p, false), puzzle)
+}
+
+// epubGrid draws the grid of p as a table, with the letters unless blank is
+// set.
+func epubGrid(p *Puzzle, blank bool) string {
+	numbers := make(map[Pos]int)
+	for _, e := range p.entries() {
+		numbers[Pos{e.Row, e.Col}] = e.Number
+	}
+	var b strings.Builder
+	b.WriteString("<table class=\"grid\">\n")
+	for r := 0; r < p.Rows; r++ {
+		b.WriteString("  <tr>")
+		for c := 0; c < p.Cols; c++ {
+			loc := Pos{r, c}
+			ch := p.Cell(r, c)
+			if ch == '#' {
+				b.WriteString("<td class=\"block\"></td>")
+				continue
+			}
+			var classes []string
+			for _, style := range []struct {
+				on    bool
+				class string
+			}{
+				{p.Marked(loc, MARK_SHADE), "shade"},
+				{p.Marked(loc, MARK_CIRCLE), "circle"},
+				{p.Bar(loc, BAR_RIGHT), "bar-right"},
+				{p.Bar(loc, BAR_BELOW), "bar-below"},
+			} {
+				if style.on {
+					classes = append(classes, style.class)
+				}
+			}
+			b.WriteString("<td")
+			if len(classes) > 0 {
+				fmt.Fprintf(&b, " class=\"%s\"", strings.Join(classes, " "))
+			}
+			b.WriteString(">")
+			if n, ok := numbers[loc]; ok {
+				fmt.Fprintf(&b, "<sup>%d</sup>", n)
+			}
+			letter := "&#160;"
+			if !blank {
+				letter = html.EscapeString(string(ch))
+			}
+			fmt.Fprintf(&b, "<span class=\"letter\">%s</span></td>", letter)
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
@@ -0,0 +1,143 @@
+// file: escpos.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The escpos export is for receipt printers, the cafe "puzzle of the day":
+// the raw ESC/POS commands printing the title, the empty grid as a raster
+// image, and the clues, then cutting the paper. Send it straight to the
+// printer, e.g. cat puzzle.bin > /dev/usb/lp0. It is laid out for 58 mm
+// paper, which 80 mm printers print too; the solution is not printed.
+//
+// Printers only have a single-byte code page, so the text is folded to
+// ASCII and what is left unprintable becomes '?'.
+
+// Receipt layout: printable dots and characters across 58 mm paper, and the
+// smallest grid cell in dots worth printing.
+const (
+	ESCPOS_DOTS     = 384
+	ESCPOS_COLUMNS  = 32
+	ESCPOS_MIN_CELL = 10
+)
+
+// ESC/POS commands.
+const (
+	ESCPOS_INIT     = "\x1b@"
+	ESCPOS_CENTER   = "\x1ba\x01"
+	ESCPOS_LEFT     = "\x1ba\x00"
+	ESCPOS_BOLD_ON  = "\x1bE\x01"
+	ESCPOS_BOLD_OFF = "\x1bE\x00"
+	ESCPOS_FEED_CUT = "\x1bd\x04\x1dVB\x00" // feed 4 lines, then a partial cut
+	ESCPOS_RASTER   = "\x1dv0\x00"          // GS v 0, normal size
+)
+
+// escposPunctuation folds typographic punctuation to ASCII.
+var escposPunctuation = strings.NewReplacer("‘", "'", "’", "'", "“", `"`, "”", `"`, "–", "-", "—", "-", "…", "...", "©", "(c)")
+
+// escposText folds s to ASCII, replacing what does not fold with '?'.
+func escposText(s string) string {
+	s = foldWord(escposPunctuation.Replace(s), accentFolds)
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r >= ' ' && r < 0x7f {
+			return r
+		}
+		return '?'
+	}, s)
+}
+
+// escposWrap splits s into lines of at most ESCPOS_COLUMNS characters, the
+// lines after the first indented by indent spaces.
+func escposWrap(s string, indent int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) > ESCPOS_COLUMNS:
+			lines = append(lines, line)
+			line = strings.Repeat(" ", indent) + word
+		default:
+			line += " " + word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// writeESCPOS writes p as ESC/POS printer commands.
+func writeESCPOS(w io.Writer, p *Puzzle) error {
+	cell := min(POST_CELL, (ESCPOS_DOTS-1)/p.Cols)
+	if cell < ESCPOS_MIN_CELL {
+		return fmt.Errorf("escpos: a %d-column grid is too wide for a receipt", p.Cols)
+	}
+	scale := 1
+	if cell >= 24 {
+		scale = 2
+	}
+
+	b := bufio.NewWriter(w)
+	b.WriteString(ESCPOS_INIT + ESCPOS_CENTER)
+	m := p.Meta
+	if m.Title != "" {
+		b.WriteString(ESCPOS_BOLD_ON)
+		for _, line := range escposWrap(escposText(m.Title), 0) {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString(ESCPOS_BOLD_OFF)
+	}
+	if m.Author != "" {
+		b.WriteString(escposText("by "+m.Author) + "\n")
+	}
+	b.WriteString("\n")
+
+	img := gridImage(p, cell, scale)
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	rowBytes := (width + 7) / 8
+	b.WriteString(ESCPOS_RASTER)
+	b.Write([]byte{byte(rowBytes), byte(rowBytes >> 8), byte(height), byte(height >> 8)})
+	for y := 0; y < height; y++ {
+		row := make([]byte, rowBytes)
+		for x := 0; x < width; x++ {
+			if img.ColorIndexAt(x, y) == 1 {
+				row[x/8] |= 0x80 >> (x % 8)
+			}
+		}
+		b.Write(row)
+	}
+	b.WriteString("\n" + ESCPOS_LEFT)
+
+	if m.Notes != "" {
+		for _, line := range escposWrap(escposText(m.Notes), 0) {
+			b.WriteString(line + "\n")
+		}
+	}
+	for _, list := range []struct {
+		title   string
+		entries []Entry
+	}{{"ACROSS", p.AcrossEntries()}, {"DOWN", p.DownEntries()}} {
+		b.WriteString("\n" + ESCPOS_BOLD_ON + list.title + ESCPOS_BOLD_OFF + "\n")
+		for _, e := range list.entries {
+			clue := p.ClueText(e) + " (" + e.lengths() + ")"
+			if e.Clue == "" {
+				clue = "(" + e.lengths() + ")"
+			}
+			if dir := otherDirection(p, e); dir != "" {
+				clue += " [" + dir + "]"
+			}
+			number := fmt.Sprintf("%d. ", e.Number)
+			for _, line := range escposWrap(number+escposText(clue), len(number)) {
+				b.WriteString(line + "\n")
+			}
+		}
+	}
+	b.WriteString(ESCPOS_FEED_CUT)
+	return b.Flush()
+}
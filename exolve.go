@@ -0,0 +1,115 @@
+// file: exolve.go
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+)
+
+// Exolve (github.com/viresh-ratnakar/exolve) renders a crossword on any web
+// page from a plain-text specification between exolve-begin and exolve-end.
+// Blocks are '.', bars follow their cell as decorators, and cells are named
+// chess-style: column letter, then the row counted from the bottom, so a1 is
+// the bottom-left cell.
+
+// writeExolve writes p as an Exolve specification. Highlights without a
+// colour become ninas (revealed once the puzzle is solved), the others
+// exolve-colour lines. Circled cells get the @ decorator and shaded ones an
+// exolve-colour line of SHADE_COLOUR.
+func writeExolve(w io.Writer, p *Puzzle) error {
+	if err := checkAcrossDown(p, "exolve"); err != nil {
+		return err
+	}
+	shaded := p.markedCells(MARK_SHADE)
+	if (len(p.highlights) > 0 || len(shaded) > 0) && p.Cols > 26 {
+		return fmt.Errorf("exolve: cannot name highlighted cells in a grid wider than 26 columns")
+	}
+	var b strings.Builder
+	h := fnv.New32a()
+	h.Write([]byte(p.String()))
+
+	b.WriteString("exolve-begin\n")
+	fmt.Fprintf(&b, "  exolve-id: crossword-%08x\n", h.Sum32())
+	fmt.Fprintf(&b, "  exolve-width: %d\n", p.Cols)
+	fmt.Fprintf(&b, "  exolve-height: %d\n", p.Rows)
+	for _, field := range []struct{ key, value string }{
+		{"exolve-title", p.Meta.Title},
+		{"exolve-setter", p.Meta.Author},
+		{"exolve-copyright", p.Meta.Copyright},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&b, "  %s: %s\n", field.key, field.value)
+		}
+	}
+	if p.Meta.Notes != "" {
+		b.WriteString("  exolve-preamble:\n")
+		for _, line := range strings.Split(p.Meta.Notes, "\n") {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+	b.WriteString("  exolve-grid:\n")
+	for r := 0; r < p.Rows; r++ {
+		b.WriteString("    ")
+		for c := 0; c < p.Cols; c++ {
+			if ch := p.Cell(r, c); ch == '#' {
+				b.WriteByte('.')
+			} else {
+				b.WriteRune(ch)
+			}
+			// bar decorators: | to the right, _ below, + both
+			if sides := p.bars[Pos{r, c}]; sides != 0 {
+				b.WriteByte(" |_+"[sides])
+			}
+			if p.Marked(Pos{r, c}, MARK_CIRCLE) {
+				b.WriteByte('@')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	for _, list := range []struct {
+		section string
+		entries []Entry
+	}{{"exolve-across", p.AcrossEntries()}, {"exolve-down", p.DownEntries()}} {
+		fmt.Fprintf(&b, "  %s:\n", list.section)
+		for _, e := range list.entries {
+			fmt.Fprintf(&b, "    %d %s(%s)\n", e.Number, clueText(p, e), e.lengths())
+		}
+	}
+	for _, hl := range p.highlights {
+		names := make([]string, len(hl.Cells))
+		for i, loc := range hl.Cells {
+			names[i] = exolveCell(p, loc)
+		}
+		if hl.Colour == "" {
+			fmt.Fprintf(&b, "  exolve-nina: %s\n", strings.Join(names, " "))
+		} else {
+			fmt.Fprintf(&b, "  exolve-colour: %s %s\n", strings.Join(names, " "), hl.Colour)
+		}
+	}
+	if len(shaded) > 0 {
+		names := make([]string, len(shaded))
+		for i, loc := range shaded {
+			names[i] = exolveCell(p, loc)
+		}
+		fmt.Fprintf(&b, "  exolve-colour: %s %s\n", strings.Join(names, " "), SHADE_COLOUR)
+	}
+	b.WriteString("exolve-end\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// clueText returns the clue, references resolved, followed by a space, or
+// nothing for an empty clue.
+func clueText(p *Puzzle, e Entry) string {
+	if e.Clue == "" {
+		return ""
+	}
+	return p.resolveClue(e.Clue) + " "
+}
+
+// exolveCell names loc in Exolve's chess notation.
+func exolveCell(p *Puzzle, loc Pos) string {
+	return fmt.Sprintf("%c%d", 'a'+loc.C, p.Rows-loc.R)
+}
@@ -0,0 +1,94 @@
+// file: explain.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// When a word never lands, the trace tells why: for every word, how many
+// heads the search tested and which check refused the ones it did not take.
+
+// Why a placement is refused; see rejection.
+const (
+	ACCEPTED         = iota
+	REJECT_BOUNDARY  // runs off the grid
+	REJECT_ADJACENT  // touches a letter of another word end-on or side by side
+	REJECT_MISMATCH  // crosses a different letter
+	REJECT_DIRECTION // shares a cell with a word on the same axis, or with two words
+)
+
+// rejectionNames are the reasons as reported, indexed by REJECT_ constant.
+var rejectionNames = [...]string{
+	REJECT_BOUNDARY:  "boundary",
+	REJECT_ADJACENT:  "adjacency",
+	REJECT_MISMATCH:  "letter mismatch",
+	REJECT_DIRECTION: "direction conflict",
+}
+
+// WordTrace counts the placements tried for one word.
+type WordTrace struct {
+	Heads      int                      `json:"heads"`      // placements tested
+	Placed     int                      `json:"placed"`     // placements that passed the checks
+	Rejections [len(rejectionNames)]int `json:"rejections"` // by REJECT_ reason; index 0 unused
+}
+
+// PlacementTrace maps every word, as placed in the grid, to what happened to
+// it. Set Options.Trace to a new map to fill it; a nil trace records nothing.
+type PlacementTrace map[string]*WordTrace
+
+// record counts one placement of word tested with the given outcome.
+func (t PlacementTrace) record(word string, reason int) {
+	if t == nil {
+		return
+	}
+	wt, ok := t[word]
+	if !ok {
+		wt = &WordTrace{}
+		t[word] = wt
+	}
+	wt.Heads++
+	if reason == ACCEPTED {
+		wt.Placed++
+	} else {
+		wt.Rejections[reason]++
+	}
+}
+
+// writeTrace prints the trace, the words that were placed least often first,
+// marking those missing from p.
+func writeTrace(w io.Writer, t PlacementTrace, p *Puzzle) {
+	words := make([]string, 0, len(t))
+	for word := range t {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		a, b := t[words[i]], t[words[j]]
+		if a.Placed != b.Placed {
+			return a.Placed < b.Placed
+		}
+		return words[i] < words[j]
+	})
+	fmt.Fprintln(w, "Placements tried per word:")
+	for _, word := range words {
+		wt := t[word]
+		var reasons []string
+		for reason, n := range wt.Rejections {
+			if n > 0 {
+				reasons = append(reasons, fmt.Sprintf("%s %d", rejectionNames[reason], n))
+			}
+		}
+		line := fmt.Sprintf("  %s: %d tested, %d fit", word, wt.Heads, wt.Placed)
+		if len(reasons) > 0 {
+			line += "; refused for " + strings.Join(reasons, ", ")
+		}
+		if p != nil {
+			if _, ok := p.Find(word); !ok {
+				line += " (not in the grid)"
+			}
+		}
+		fmt.Fprintln(w, line)
+	}
+}
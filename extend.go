@@ -0,0 +1,96 @@
+// file: extend.go
+package main
+
+import (
+	"io"
+	"log/slog"
+	"sort"
+)
+
+// extendPuzzle adds words to an existing puzzle, keeping every entry where it
+// is. The base is seeded into the generator's maps the same way tiles are,
+// and the new words are placed with the regular backtracker; words it cannot
+// fit together are then tried one at a time. The grid is opts.GridSize, or
+// the base's size if that is larger. Clues of the base entries are kept.
+func extendPuzzle(base *Puzzle, words []string, opts Options) *Puzzle {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	gridSize := max(opts.GridSize, base.Rows, base.Cols)
+
+	existing := base.entries()
+	sort.Slice(existing, func(i, j int) bool { return existing[i].Order < existing[j].Order })
+	var placed []tilePlacement
+	have := make(map[string]bool)
+	for _, e := range existing {
+		placed = append(placed, tilePlacement{Pos{e.Row, e.Col}, e.Direction, e.Word})
+		have[e.Word] = true
+	}
+
+	display := make(map[string]string)
+	var fresh []string
+	for _, w := range words {
+		placedWord := gridForm(w, opts.folds())
+		if !have[placedWord] {
+			have[placedWord] = true
+			display[placedWord] = w
+			fresh = append(fresh, placedWord)
+		}
+	}
+	sort.SliceStable(fresh, func(i, j int) bool { return len([]rune(fresh[i])) > len([]rune(fresh[j])) })
+
+	var added []tilePlacement
+	ok := false
+	for iter := 0; iter < opts.MaxIter && !ok && len(fresh) > 0; iter += TILE_ITER {
+		added, ok = fillTile(Pos{}, gridSize, fresh, placed, opts.MaxDepth, newRand())
+	}
+
+	grid := initGrid(gridSize)
+	cellDir := initCellDir(gridSize)
+	index := newGridIndex()
+	seedTile(Pos{}, gridSize, placed, grid, cellDir, index)
+	if ok {
+		seedTile(Pos{}, gridSize, added, grid, cellDir, index)
+	} else {
+		var missed []string
+		added, missed = placeSingly(fresh, gridSize, grid, cellDir, index)
+		for _, w := range missed {
+			logger.Warn("word does not fit the imported puzzle", "word", display[w])
+		}
+	}
+	placed = append(placed, added...)
+
+	classification := map[int][]Placement{HORIZONTAL: {}, VERTICAL: {}}
+	for i, p := range placed {
+		classification[p.direction] = append(classification[p.direction], Placement{Loc: gridSize*p.head.R + p.head.C, Word: p.word, Order: i})
+	}
+	for _, e := range existing {
+		display[e.Word] = e.Display
+	}
+	p := newPuzzle(gridSize, grid, classification, display)
+	p.Meta = base.Meta
+
+	// numbers change as words are added, so clues are matched by position
+	for _, list := range [][]Entry{p.across, p.down} {
+		for i, e := range list {
+			for _, old := range existing {
+				if old.Row == e.Row && old.Col == e.Col && old.Direction == e.Direction && old.Word == e.Word {
+					list[i].Clue = old.Clue
+				}
+			}
+		}
+	}
+	return p
+}
+
+// regenerate keeps the placements of the locked words of p and fills the
+// grid again with the rest of words, e.g. after words were swapped in the
+// list. Words of p that are neither locked nor in words are dropped.
+func regenerate(p *Puzzle, locked []string, words []string, opts Options) (*Puzzle, error) {
+	base, err := p.Keep(locked)
+	if err != nil {
+		return nil, err
+	}
+	return extendPuzzle(base, words, opts), nil
+}
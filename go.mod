@@ -2,14 +2,14 @@ module crossword
 
 go 1.25.1
 
+require github.com/cheggaaa/pb/v3 v3.1.7
+
 require (
 	github.com/VividCortex/ewma v1.2.0 // indirect
-	github.com/cheggaaa/pb/v3 v3.1.7 // indirect
 	github.com/fatih/color v1.18.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
-	github.com/schollz/progressbar v1.0.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 )
@@ -0,0 +1,264 @@
+// file: ipuz.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ipuz (ipuz.org) is the JSON puzzle format most crossword software exports.
+// Only what a Puzzle can hold is read and written: the metadata, the
+// solution grid, the bars of barred grids and circled and shaded cells (from
+// the styles of the puzzle cells), and the across and down clues. Omitted
+// cells (null) are treated as blocks.
+
+type ipuzFile struct {
+	Kind       []string `json:"kind"`
+	Dimensions struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"dimensions"`
+	Title     string                       `json:"title"`
+	Author    string                       `json:"author"`
+	Copyright string                       `json:"copyright"`
+	Notes     string                       `json:"notes"`
+	Block     string                       `json:"block"`
+	Puzzle    [][]json.RawMessage          `json:"puzzle"`
+	Solution  [][]json.RawMessage          `json:"solution"`
+	Clues     map[string][]json.RawMessage `json:"clues"`
+}
+
+// readIpuz parses an ipuz crossword.
+func readIpuz(r io.Reader) (*Puzzle, error) {
+	var f ipuzFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("ipuz: %w", err)
+	}
+	crossword := false
+	for _, kind := range f.Kind {
+		crossword = crossword || strings.Contains(kind, "ipuz.org/crossword")
+	}
+	if !crossword {
+		return nil, fmt.Errorf("ipuz: not a crossword (kind %v)", f.Kind)
+	}
+	if f.Block == "" {
+		f.Block = "#"
+	}
+	rows, cols := f.Dimensions.Height, f.Dimensions.Width
+	if len(f.Solution) != rows {
+		return nil, fmt.Errorf("ipuz: solution has %d rows, dimensions say %d", len(f.Solution), rows)
+	}
+
+	grid := make(map[Pos]rune)
+	for r, row := range f.Solution {
+		if len(row) != cols {
+			return nil, fmt.Errorf("ipuz: solution row %d has %d cells, dimensions say %d", r+1, len(row), cols)
+		}
+		for c, raw := range row {
+			value, err := ipuzCellValue(raw)
+			if err != nil {
+				return nil, fmt.Errorf("ipuz: cell (%d, %d): %w", r+1, c+1, err)
+			}
+			if value == "" || value == f.Block {
+				continue
+			}
+			if utf8.RuneCountInString(value) != 1 {
+				return nil, fmt.Errorf("ipuz: cell (%d, %d) holds %q; rebus cells are not supported", r+1, c+1, value)
+			}
+			ch, _ := utf8.DecodeRuneInString(strings.ToUpper(value))
+			grid[Pos{r, c}] = ch
+		}
+	}
+	bars, marks := ipuzStyles(f.Puzzle)
+	p := puzzleFromBarredGrid(rows, cols, grid, bars)
+	p.Meta = Metadata{Title: f.Title, Author: f.Author, Copyright: f.Copyright, Notes: f.Notes}
+	for loc, kind := range marks {
+		p.Mark([]Pos{loc}, kind)
+	}
+
+	for dirName, list := range f.Clues {
+		// keys are "Across" and "Down", optionally with a display name ("Across:Clues")
+		name, _, _ := strings.Cut(dirName, ":")
+		var entries []Entry
+		switch strings.ToLower(name) {
+		case "across":
+			entries = p.across
+		case "down":
+			entries = p.down
+		default:
+			continue
+		}
+		for _, raw := range list {
+			number, text, err := ipuzClue(raw)
+			if err != nil {
+				return nil, fmt.Errorf("ipuz: %s clue: %w", name, err)
+			}
+			for i := range entries {
+				if fmt.Sprint(entries[i].Number) == number {
+					entries[i].Clue = text
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// ipuzStyle is the part of a cell style a Puzzle keeps.
+type ipuzStyle struct {
+	Barred    string `json:"barred,omitempty"`    // sides with a bar: T, R, B, L
+	Shapebg   string `json:"shapebg,omitempty"`   // "circle" for a circled cell
+	Highlight bool   `json:"highlight,omitempty"` // a shaded cell
+	Color     string `json:"color,omitempty"`     // background colour, also read as shading
+}
+
+// ipuzStyles collects the bars and marks of the styled puzzle cells
+// ({"cell": 1, "style": {...}}). Top and left bars are stored on the
+// neighbouring cell.
+func ipuzStyles(cells [][]json.RawMessage) (bars, marks map[Pos]int) {
+	bars, marks = make(map[Pos]int), make(map[Pos]int)
+	for r, row := range cells {
+		for c, raw := range row {
+			var cell struct {
+				Style ipuzStyle `json:"style"`
+			}
+			if json.Unmarshal(raw, &cell) != nil {
+				continue // a plain number, "#" or null
+			}
+			for _, side := range strings.ToUpper(cell.Style.Barred) {
+				switch {
+				case side == 'R':
+					bars[Pos{r, c}] |= BAR_RIGHT
+				case side == 'B':
+					bars[Pos{r, c}] |= BAR_BELOW
+				case side == 'L' && c > 0:
+					bars[Pos{r, c - 1}] |= BAR_RIGHT
+				case side == 'T' && r > 0:
+					bars[Pos{r - 1, c}] |= BAR_BELOW
+				}
+			}
+			if cell.Style.Shapebg == "circle" {
+				marks[Pos{r, c}] |= MARK_CIRCLE
+			}
+			if cell.Style.Highlight || cell.Style.Color != "" {
+				marks[Pos{r, c}] |= MARK_SHADE
+			}
+		}
+	}
+	return bars, marks
+}
+
+// ipuzCell is a puzzle cell as written: its clue number (0 for none) and,
+// for barred or marked cells, a style.
+type ipuzCell struct {
+	Cell  int        `json:"cell"`
+	Style *ipuzStyle `json:"style,omitempty"`
+}
+
+// writeIpuz writes p as an ipuz crossword with its solution, bars, circled
+// and shaded cells and clues.
+func writeIpuz(w io.Writer, p *Puzzle) error {
+	if err := checkAcrossDown(p, "ipuz"); err != nil {
+		return err
+	}
+	numbers := make(map[Pos]int)
+	for _, e := range p.entries() {
+		numbers[Pos{e.Row, e.Col}] = e.Number
+	}
+	out := map[string]any{
+		"version":    "http://ipuz.org/v2",
+		"kind":       []string{"http://ipuz.org/crossword#1"},
+		"dimensions": map[string]int{"width": p.Cols, "height": p.Rows},
+		"block":      "#",
+	}
+	for key, value := range map[string]string{"title": p.Meta.Title, "author": p.Meta.Author, "copyright": p.Meta.Copyright, "notes": p.Meta.Notes} {
+		if value != "" {
+			out[key] = value
+		}
+	}
+	puzzle := make([][]any, p.Rows)
+	solution := make([][]string, p.Rows)
+	for r := 0; r < p.Rows; r++ {
+		puzzle[r] = make([]any, p.Cols)
+		solution[r] = make([]string, p.Cols)
+		for c := 0; c < p.Cols; c++ {
+			loc := Pos{r, c}
+			ch := p.Cell(r, c)
+			if ch == '#' {
+				puzzle[r][c], solution[r][c] = "#", "#"
+				continue
+			}
+			solution[r][c] = string(ch)
+			var style ipuzStyle
+			if p.Bar(loc, BAR_RIGHT) {
+				style.Barred += "R"
+			}
+			if p.Bar(loc, BAR_BELOW) {
+				style.Barred += "B"
+			}
+			if p.Marked(loc, MARK_CIRCLE) {
+				style.Shapebg = "circle"
+			}
+			style.Highlight = p.Marked(loc, MARK_SHADE)
+			if style == (ipuzStyle{}) {
+				puzzle[r][c] = numbers[loc]
+			} else {
+				puzzle[r][c] = ipuzCell{numbers[loc], &style}
+			}
+		}
+	}
+	out["puzzle"], out["solution"] = puzzle, solution
+	clues := make(map[string][][2]any)
+	for name, list := range map[string][]Entry{"Across": p.AcrossEntries(), "Down": p.DownEntries()} {
+		clues[name] = [][2]any{}
+		for _, e := range list {
+			clues[name] = append(clues[name], [2]any{e.Number, p.ClueText(e)})
+		}
+	}
+	out["clues"] = clues
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// ipuzCellValue returns a solution cell as a string: a letter, the block
+// marker, or "" for null and empty cells. Cells may be plain values or
+// objects with a "value".
+func ipuzCellValue(raw json.RawMessage) (string, error) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return "", nil // 0: an empty cell
+	case map[string]any:
+		s, _ := v["value"].(string)
+		return s, nil
+	}
+	return "", fmt.Errorf("unexpected value %s", raw)
+}
+
+// ipuzClue returns the number and text of a clue, given either as
+// [number, "text"] or as {"number": ..., "clue": "text"}.
+func ipuzClue(raw json.RawMessage) (number, text string, err error) {
+	var pair []any
+	if json.Unmarshal(raw, &pair) == nil && len(pair) >= 2 {
+		text, _ := pair[1].(string)
+		return fmt.Sprint(pair[0]), text, nil
+	}
+	var obj struct {
+		Number any    `json:"number"`
+		Clue   string `json:"clue"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return "", "", err
+	}
+	return fmt.Sprint(obj.Number), obj.Clue, nil
+}
@@ -0,0 +1,75 @@
+// file: ipuz_test.go
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIpuzRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		rows          []string
+		bars          map[Pos]int
+		meta          Metadata
+		clues         map[string]string
+		circle, shade []Pos
+	}{
+		{"grid only", []string{"CAT", "A#O", "BOP"}, nil, Metadata{}, nil, nil, nil},
+		{"clued with metadata", []string{"CAT#", "A#OX", "BOP#"}, nil,
+			Metadata{Title: "Mini", Author: "A. Setter", Copyright: "2024", Notes: "Line one\nline two"},
+			map[string]string{"A1": "Pet", "A3": "Ruminant", "D1": "Taxi", "D2": "Summit"}, nil, nil},
+		{"circled and shaded", []string{"CAT", "A#O", "BOP"}, nil, Metadata{}, nil,
+			[]Pos{{0, 0}, {2, 2}}, []Pos{{0, 2}, {2, 2}}},
+		{"barred", []string{"TEA", "ORB", "NAB"}, map[Pos]int{{1, 0}: BAR_RIGHT, {0, 2}: BAR_BELOW},
+			Metadata{}, map[string]string{"A1": "Drink", "D1": "Weight"}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPuzzle(tt.rows, tt.bars, tt.clues)
+			p.Meta = tt.meta
+			p.Mark(tt.circle, MARK_CIRCLE)
+			p.Mark(tt.shade, MARK_SHADE)
+			var b bytes.Buffer
+			if err := writeIpuz(&b, p); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readIpuz(bytes.NewReader(b.Bytes()))
+			if err != nil {
+				t.Fatalf("%v in\n%s", err, b.String())
+			}
+			if got.String() != p.String() {
+				t.Errorf("grid\n%s\nwant\n%s", got, p)
+			}
+			if got.Meta != p.Meta {
+				t.Errorf("metadata %+v, want %+v", got.Meta, p.Meta)
+			}
+			for r := 0; r < p.Rows; r++ {
+				for c := 0; c < p.Cols; c++ {
+					loc := Pos{r, c}
+					for _, side := range []int{BAR_RIGHT, BAR_BELOW} {
+						if got.Bar(loc, side) != p.Bar(loc, side) {
+							t.Errorf("cell %v: bar %d is %v, want %v", loc, side, got.Bar(loc, side), p.Bar(loc, side))
+						}
+					}
+					for _, kind := range []int{MARK_CIRCLE, MARK_SHADE} {
+						if got.Marked(loc, kind) != p.Marked(loc, kind) {
+							t.Errorf("cell %v: mark %d is %v, want %v", loc, kind, got.Marked(loc, kind), p.Marked(loc, kind))
+						}
+					}
+				}
+			}
+			for i, list := range [][2][]Entry{{got.AcrossEntries(), p.AcrossEntries()}, {got.DownEntries(), p.DownEntries()}} {
+				if len(list[0]) != len(list[1]) {
+					t.Fatalf("direction %d: %d entries, want %d", i, len(list[0]), len(list[1]))
+				}
+				for j, e := range list[0] {
+					w := list[1][j]
+					if e.Number != w.Number || e.Row != w.Row || e.Col != w.Col || e.Word != w.Word || e.Clue != w.Clue {
+						t.Errorf("entry %d %s %q at %d,%d, want %d %s %q at %d,%d", e.Number, e.Word, e.Clue, e.Row, e.Col, w.Number, w.Word, w.Clue, w.Row, w.Col)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// file: lang.go
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// A language profile holds what differs between puzzles in one language and
+// another: the letters a grid may hold, how accented letters fold when
+// accent folding is on, the letter mix of word search filler and the word
+// list BUILTIN_WORDLIST stands for. -lang picks the profile; without it the
+// generator behaves as before, which is English except that word search
+// filler is drawn from the hidden words.
+//
+// German grids write Ä, Ö, Ü and ß as AE, OE, UE and SS; Spanish grids keep
+// Ñ as a letter of its own.
+
+// DEFAULT_LANGUAGE is the profile used when none is chosen.
+const DEFAULT_LANGUAGE = "en"
+
+// language is a -lang profile.
+type language struct {
+	name     string
+	alphabet string           // letters a grid may hold, in order
+	letters  map[rune]float64 // letter frequencies in running text, in percent
+	list     string           // file of the built-in word list, under wordlists/
+	folds    map[rune]string  // accent folding; nil for accentFolds
+}
+
+// languages are the -lang choices.
+var languages = map[string]language{
+	"en": {name: "English", alphabet: "ABCDEFGHIJKLMNOPQRSTUVWXYZ", list: "english.txt",
+		letters: map[rune]float64{
+			'E': 12.7, 'T': 9.1, 'A': 8.2, 'O': 7.5, 'I': 7.0, 'N': 6.7, 'S': 6.3, 'H': 6.1, 'R': 6.0,
+			'D': 4.3, 'L': 4.0, 'C': 2.8, 'U': 2.8, 'M': 2.4, 'W': 2.4, 'F': 2.2, 'G': 2.0, 'Y': 2.0,
+			'P': 1.9, 'B': 1.5, 'V': 1.0, 'K': 0.8, 'J': 0.15, 'X': 0.15, 'Q': 0.1, 'Z': 0.07,
+		}},
+	"de": {name: "German", alphabet: "ABCDEFGHIJKLMNOPQRSTUVWXYZ", list: "german.txt",
+		letters: map[rune]float64{
+			'E': 16.4, 'N': 9.8, 'I': 7.6, 'S': 7.3, 'R': 7.0, 'A': 6.5, 'T': 6.2, 'D': 5.1, 'H': 4.6,
+			'U': 4.2, 'L': 3.4, 'G': 3.0, 'C': 2.7, 'O': 2.6, 'M': 2.5, 'B': 1.9, 'W': 1.9, 'F': 1.7,
+			'K': 1.4, 'Z': 1.1, 'P': 0.8, 'V': 0.8, 'J': 0.3, 'Y': 0.04, 'X': 0.03, 'Q': 0.02,
+		},
+		folds: withFolds(map[rune]string{'Ä': "AE", 'Ö': "OE", 'Ü': "UE"}, nil)},
+	"es": {name: "Spanish", alphabet: "ABCDEFGHIJKLMNÑOPQRSTUVWXYZ", list: "spanish.txt",
+		letters: map[rune]float64{
+			'E': 13.7, 'A': 12.5, 'O': 8.7, 'S': 8.0, 'R': 6.9, 'N': 6.7, 'I': 6.2, 'D': 5.9, 'L': 5.0,
+			'C': 4.7, 'T': 4.6, 'U': 3.9, 'M': 3.2, 'P': 2.5, 'B': 1.4, 'G': 1.0, 'V': 0.9, 'Y': 0.9,
+			'Q': 0.9, 'H': 0.7, 'F': 0.7, 'Z': 0.5, 'J': 0.4, 'Ñ': 0.3, 'X': 0.2, 'K': 0.01, 'W': 0.01,
+		},
+		folds: withFolds(nil, []rune{'Ñ'})},
+	"it": {name: "Italian", alphabet: "ABCDEFGHIJKLMNOPQRSTUVWXYZ", list: "italian.txt",
+		letters: map[rune]float64{
+			'E': 11.8, 'A': 11.7, 'I': 11.3, 'O': 9.8, 'N': 6.9, 'L': 6.5, 'R': 6.4, 'T': 5.6, 'S': 5.0,
+			'C': 4.5, 'D': 3.7, 'P': 3.1, 'U': 3.0, 'M': 2.5, 'V': 2.1, 'G': 1.6, 'H': 1.5, 'F': 1.2,
+			'B': 0.9, 'Z': 0.5, 'Q': 0.5, 'J': 0.01, 'K': 0.01, 'W': 0.01, 'X': 0.01, 'Y': 0.01,
+		}},
+}
+
+// withFolds returns accentFolds with the folds in change added or replaced
+// and the letters in keep left as they are.
+func withFolds(change map[rune]string, keep []rune) map[rune]string {
+	folds := make(map[rune]string, len(accentFolds))
+	for r, s := range accentFolds {
+		folds[r] = s
+	}
+	for r, s := range change {
+		folds[r] = s
+	}
+	for _, r := range keep {
+		delete(folds, r)
+	}
+	return folds
+}
+
+// languageNames lists the -lang choices for help and error messages.
+func languageNames() []string {
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupLanguage returns the profile called name, DEFAULT_LANGUAGE for "".
+func lookupLanguage(name string) (language, error) {
+	if name == "" {
+		name = DEFAULT_LANGUAGE
+	}
+	lang, ok := languages[name]
+	if !ok {
+		return language{}, fmt.Errorf("unknown language %q (want %s)", name, strings.Join(languageNames(), " or "))
+	}
+	return lang, nil
+}
+
+// foldTable returns the accent folding of the profile.
+func (l language) foldTable() map[rune]string {
+	if l.folds == nil {
+		return accentFolds
+	}
+	return l.folds
+}
+
+// hasLetter reports whether r, in upper case, belongs to the alphabet.
+func (l language) hasLetter(r rune) bool {
+	return strings.ContainsRune(l.alphabet, r)
+}
+
+// randomLetter draws a letter of the alphabet by its frequency.
+func (l language) randomLetter() rune {
+	total := 0.0
+	for _, f := range l.letters {
+		total += f
+	}
+	pick := rand.Float64() * total
+	for _, r := range l.alphabet {
+		if pick -= l.letters[r]; pick < 0 {
+			return r
+		}
+	}
+	return []rune(l.alphabet)[0]
+}
+
+// folds returns the accent folding gridForm applies under opts: nil without
+// FoldAccents, otherwise that of the language profile.
+func (opts Options) folds() map[rune]string {
+	if !opts.FoldAccents {
+		return nil
+	}
+	lang, err := lookupLanguage(opts.Language)
+	if err != nil {
+		return accentFolds
+	}
+	return lang.foldTable()
+}
@@ -0,0 +1,167 @@
+// file: llmclues.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// llmClues drafts clues with a chat-completion endpoint that speaks the
+// OpenAI API (OpenAI itself, or a local server such as llama.cpp or Ollama).
+// Answers are sent one at a time, at most perMinute requests a minute, and
+// every clue is cached so reruns over the same list cost nothing.
+type llmClues struct {
+	endpoint   string // full URL of the chat/completions route
+	model      string
+	apiKey     string // sent as a bearer token if set
+	difficulty string // easy, medium or hard
+	interval   time.Duration
+	client     *http.Client
+
+	cachePath string            // JSON file the cache is kept in; empty keeps it in memory
+	cache     map[string]string // difficulty + "|" + word -> clue
+	last      time.Time         // time of the last request
+}
+
+// newLLMClues returns a provider for the endpoint, loading cachePath if it
+// exists. endpoint may be the API base URL (".../v1").
+func newLLMClues(endpoint, model, apiKey, difficulty string, perMinute int, cachePath string) (*llmClues, error) {
+	switch difficulty {
+	case "easy", "medium", "hard":
+	default:
+		return nil, fmt.Errorf("unknown clue difficulty %q (want easy, medium or hard)", difficulty)
+	}
+	if perMinute < 1 {
+		return nil, fmt.Errorf("clue request rate must be at least 1 a minute, got %d", perMinute)
+	}
+	if !strings.HasSuffix(endpoint, "/chat/completions") {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/chat/completions"
+	}
+	l := &llmClues{
+		endpoint:   endpoint,
+		model:      model,
+		apiKey:     apiKey,
+		difficulty: difficulty,
+		interval:   time.Minute / time.Duration(perMinute),
+		client:     &http.Client{Timeout: 60 * time.Second},
+		cachePath:  cachePath,
+		cache:      make(map[string]string),
+	}
+	if cachePath != "" {
+		data, err := os.ReadFile(cachePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &l.cache); err != nil {
+				return nil, fmt.Errorf("%s: %w", cachePath, err)
+			}
+		}
+	}
+	return l, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (l *llmClues) Clue(word string) (string, error) {
+	key := l.difficulty + "|" + word
+	if clue, ok := l.cache[key]; ok {
+		return clue, nil
+	}
+
+	if wait := l.interval - time.Since(l.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	l.last = time.Now()
+
+	body, err := json.Marshal(chatRequest{
+		Model: l.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: "You write crossword clues. Reply with the clue only: no answer, no quotes, no enumeration."},
+			{Role: "user", Content: fmt.Sprintf("Write one %s crossword clue for the answer %s.", l.difficulty, word)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, l.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.apiKey)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s: %.200s", l.endpoint, resp.Status, data)
+	}
+	var out chatResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", fmt.Errorf("%s: %w", l.endpoint, err)
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("%s: empty response", l.endpoint)
+	}
+
+	clue := strings.Trim(strings.TrimSpace(out.Choices[0].Message.Content), `"`)
+	// a clue that gives the answer away is no use
+	if clue == "" || strings.Contains(strings.ToUpper(clue), strings.ToUpper(word)) {
+		return "", errNoClue
+	}
+	l.cache[key] = clue
+	return clue, l.saveCache()
+}
+
+func (l *llmClues) saveCache() error {
+	if l.cachePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(l.cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.cachePath, data, 0o644)
+}
+
+// chainClues asks each provider in turn until one has a clue.
+type chainClues []ClueProvider
+
+func (c chainClues) Clue(word string) (string, error) {
+	for _, provider := range c {
+		clue, err := provider.Clue(word)
+		if !errors.Is(err, errNoClue) {
+			return clue, err
+		}
+	}
+	return "", errNoClue
+}
@@ -0,0 +1,76 @@
+// file: lookup.go
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// The anagram and match subcommands look words up in the dictionary solve
+// uses, for the moments spent fixing a tricky corner by hand:
+//
+//	crossword anagram RETAINS
+//	crossword match 'A?T??'
+//
+// anagram lists the words using exactly the given letters, where '?' is a
+// blank standing for any letter; match lists the words fitting a pattern,
+// where '?', '.' or '_' stands for any letter. Both print one word per line,
+// best-scoring first, and take solve's dictionary flags.
+
+// runAnagram is main for the anagram subcommand. Returns the process exit
+// code.
+func runAnagram(args []string, w io.Writer) int {
+	return runLookup("anagram", "LETTERS", args, w, func(d *dictionary, query string) []string {
+		return d.anagrams(query)
+	})
+}
+
+// runMatch is main for the match subcommand. Returns the process exit code.
+func runMatch(args []string, w io.Writer) int {
+	return runLookup("match", "PATTERN", args, w, func(d *dictionary, query string) []string {
+		return d.match(strings.NewReplacer(".", "?", "_", "?").Replace(query))
+	})
+}
+
+// runLookup parses the flags and the single argument of a lookup
+// subcommand, and prints the words find returns for it.
+func runLookup(name, arg string, args []string, w io.Writer, find func(d *dictionary, query string) []string) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: crossword %s [flags] %s\n", name, arg)
+		fs.PrintDefaults()
+	}
+	max := fs.Int("max", 0, "words listed at most (0 = all)")
+	var df dictFlags
+	df.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
+	if fs.NArg() != 1 || strings.TrimSpace(fs.Arg(0)) == "" {
+		fs.Usage()
+		return EXIT_USAGE
+	}
+	query := strings.ToUpper(strings.TrimSpace(fs.Arg(0)))
+
+	dict, code := df.load()
+	if dict == nil {
+		return code
+	}
+	words := find(dict, query)
+	if len(words) == 0 {
+		fmt.Fprintf(os.Stderr, "no words for %s\n", query)
+		return EXIT_OK
+	}
+	if *max > 0 && len(words) > *max {
+		words = words[:*max]
+	}
+	for _, word := range words {
+		fmt.Fprintln(w, word)
+	}
+	return EXIT_OK
+}
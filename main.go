@@ -0,0 +1,1006 @@
+// file: main.go
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cheggaaa/pb/v3"
+)
+
+// cli holds the inputs of one command-line run.
+type cli struct {
+	gridSize         int
+	autoSize         bool
+	reqIntersections int
+	maxIter          int
+	maxDepth         int
+	minDensity       float64
+	maxEmpty         int
+	minCrossings     int
+	allowIslands     bool
+	mostConstrained  bool
+	restart          string
+	restartUnit      int
+	strategy         string
+	memoMB           int
+	directions       string
+	foldAccents      bool
+	lang             string
+	showBlank        bool
+	showKey          bool
+	wordBank         bool
+	words            []string
+
+	quiet     bool
+	verbose   bool
+	logFormat string
+	count     int
+	perPuzzle int
+	profile   string
+	cellSize  float64
+	theme     string
+	qrURL     string
+	stats     bool
+	explain   bool
+	dot       string
+	dotLimit  int
+	replay    string
+	top       int
+	wordFiles string
+	out       string
+	archive   string
+	upload    string
+	post      string
+	noColor   bool
+	format    string
+	crop      bool
+	rtl       bool
+	margin    int
+	mode      string
+	clueFiles string
+	export    string
+	imports   string
+	extend    bool
+	lock      string
+	highlight string
+	meta      Metadata
+	circle    string
+	shade     string
+	scanDict  string
+	blocklist string
+	minLen    int
+	maxLen    int
+	maxWords  int
+	freqFile  string
+	config    string
+	// answers and clues from the -config file's hints; nil without one
+	configClues dictClues
+
+	llmEndpoint    string
+	llmModel       string
+	llmKeyEnv      string
+	llmRate        int
+	llmCache       string
+	clueDifficulty string
+}
+
+// parseCLI fills a cli from the defaults below and the given arguments.
+func parseCLI(name string, args []string) (*cli, error) {
+	c := &cli{
+		// === user-editable inputs ===
+		gridSize:         DEFAULT_SIZE,
+		autoSize:         false,                // ignore gridSize and use the smallest grid that meets the requirements
+		reqIntersections: DEFAULT_SIZE - 3,     // minimum required intersecting cells
+		maxIter:          DEFAULT_ITERATIONS,   // number of shuffles to try
+		maxDepth:         DEFAULT_DEPTH,        // placements tried per shuffle; caps a growing restart schedule
+		minDensity:       0,                    // minimum % of cells holding a letter (0 = no limit)
+		maxEmpty:         0,                    // maximum empty cells inside the words' bounding box (0 = no limit)
+		minCrossings:     0,                    // every word must cross at least this many others (0 = no limit)
+		allowIslands:     false,                // accept grids whose words form several unconnected groups
+		mostConstrained:  false,                // try the words with the fewest places to go first (less backtracking, slower steps)
+		restart:          "fixed",              // depth budget per shuffle: fixed (maxDepth each), luby or geometric (growing, capped by maxDepth)
+		restartUnit:      DEFAULT_RESTART_UNIT, // first budget of the luby and geometric schedules
+		strategy:         "dfs",                // search algorithm
+		memoMB:           DEFAULT_MEMO_MB,      // memory for remembering dead ends across shuffles (0 = off)
+		directions:       "",                   // directions words may run in, e.g. "right,down,left,up,down-right" (empty = the mode's usual ones)
+		foldAccents:      false,                // place É as E, Ñ as N etc.; clues keep the accented form
+		showBlank:        true,                 // also print the empty puzzle for solvers
+		showKey:          true,                 // word searches, codewords and kriss-krosses: also print the answer key
+		wordBank:         false,                // crosswords: list the answers alphabetically under the empty puzzle
+		words: []string{
+			"INTEGRINS", "ANGIOGENESIS", "ALLOSTASIS", "INFLAMMATION", "ASTROCYTES", "MICROGLIA",
+			"MICROGLIA", "HYPOXIA", "MALARIA", "VIRULENCE", "PARKINSON",
+		},
+		// ============================
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&c.config, "config", "", "TOML or YAML `file` describing the build: requirements.toml keys, hints, words and any of these flags by name (flags given here win)")
+	fs.StringVar(&c.lang, "lang", "", "language profile for accent folding, word search filler and the builtin word list: "+strings.Join(languageNames(), " or ")+" (default English)")
+	fs.StringVar(&c.mode, "mode", "crossword", "puzzle type: crossword, codeword, krisskross or wordsearch")
+	fs.BoolVar(&c.quiet, "quiet", false, "only log warnings and errors, and hide the progress bar")
+	fs.StringVar(&c.logFormat, "log-format", "text", "log format on stderr: text or json")
+	fs.BoolVar(&c.verbose, "v", false, "log generation events (debug level)")
+	fs.BoolVar(&c.explain, "explain", false, "print per word how many placements were tried and why they were refused to stderr, to see why a word never lands")
+	fs.StringVar(&c.dot, "dot", "", "write the search tree of the first puzzle to this Graphviz DOT `file`")
+	fs.IntVar(&c.dotLimit, "dot-limit", SEARCH_TREE_LIMIT, "most placements written to the -dot file")
+	fs.StringVar(&c.replay, "replay", "", "write how the first puzzle was built, word by word, to this `file`: an animated GIF if it ends in .gif, else a JSON event log")
+	fs.BoolVar(&c.stats, "stats", false, "print generation statistics (shuffles, placements, backtracks, time, best score over time) to stderr")
+	fs.IntVar(&c.count, "count", 1, "number of distinct puzzles to generate")
+	fs.IntVar(&c.perPuzzle, "per-puzzle", 0, "book: words drawn from a single -wordfile pool for each puzzle (0 = all of them)")
+	fs.StringVar(&c.profile, "profile", "standard", "book: page look, "+strings.Join(profileNames(), " or ")+" (big cells, heavy lines and large bold clues)")
+	fs.Float64Var(&c.cellSize, "cell-size", 0, "book: largest grid cell in points, overriding the theme's (0 = the theme's)")
+	fs.StringVar(&c.qrURL, "qr-url", "", "book: put a QR code of this URL on every puzzle page; {id} is replaced by the puzzle ID and {n} by its number")
+	fs.StringVar(&c.theme, "theme", "", "book: look of the pages, "+strings.Join(themeNames(), ", ")+" or a theme .json file (default: the -profile's)")
+	fs.IntVar(&c.top, "top", 1, "write the `k` best distinct layouts of one search instead of only the best, to pick from")
+	fs.StringVar(&c.wordFiles, "wordfile", "", "comma-separated word list files (one word per line); several files are used in rotation; - reads standard input, http(s) URLs are downloaded, and builtin is the built-in list of -lang")
+	fs.StringVar(&c.clueFiles, "clues", "", "comma-separated clue files (WordNet data files or WORD<tab>clue lines) used to fill in clues")
+	fs.StringVar(&c.llmEndpoint, "llm-endpoint", "", "OpenAI-compatible API URL used to draft clues the -clues files lack, e.g. https://api.openai.com/v1")
+	fs.StringVar(&c.llmModel, "llm-model", "gpt-4o-mini", "model name sent to -llm-endpoint")
+	fs.StringVar(&c.llmKeyEnv, "llm-key-env", "OPENAI_API_KEY", "environment variable holding the -llm-endpoint API key")
+	fs.IntVar(&c.llmRate, "llm-rate", 30, "maximum clue requests per minute")
+	fs.StringVar(&c.llmCache, "llm-cache", "clue-cache.json", "file drafted clues are cached in (empty: no cache file)")
+	fs.StringVar(&c.clueDifficulty, "difficulty", "medium", "difficulty of drafted clues: easy, medium or hard")
+	fs.StringVar(&c.out, "out", "", "write puzzle i to <out>-<i>.txt instead of stdout (default \"puzzle\" when -count > 1, except with -export ndjson); book: the .pdf or .epub file to write")
+	fs.BoolVar(&c.noColor, "no-color", false, "plain text output without ANSI colors")
+	fs.BoolVar(&c.autoSize, "auto-size", c.autoSize, "use the smallest grid that meets the requirements instead of the fixed size")
+	fs.BoolVar(&c.allowIslands, "allow-islands", c.allowIslands, "accept grids whose words form several unconnected groups")
+	fs.BoolVar(&c.mostConstrained, "most-constrained", c.mostConstrained, "at each step try first the words with the fewest places to go")
+	fs.StringVar(&c.restart, "restart", c.restart, "depth budget per shuffle: "+strings.Join(restartNames(), ", ")+"; the growing ones are capped by the maximum depth")
+	fs.IntVar(&c.restartUnit, "restart-unit", c.restartUnit, "first depth budget of a growing -restart schedule")
+	fs.StringVar(&c.strategy, "strategy", c.strategy, "search algorithm: "+strings.Join(strategyNames(), ", "))
+	fs.IntVar(&c.memoMB, "memo-mb", c.memoMB, "megabytes for remembering dead ends across shuffles (0 = off)")
+	fs.StringVar(&c.directions, "directions", c.directions, "comma-separated directions words may run in, from "+strings.Join(directionNames[:], ", ")+"; crosswords start with the first (default: right,down; all eight for word searches)")
+	fs.Float64Var(&c.minDensity, "density", c.minDensity, "minimum percentage of grid cells holding a letter (0 = no limit)")
+	fs.IntVar(&c.maxEmpty, "max-empty", c.maxEmpty, "maximum empty cells inside the words' bounding box (0 = no limit)")
+	fs.IntVar(&c.minCrossings, "min-crossings", c.minCrossings, "every word must cross at least this many other words (0 = no limit)")
+	fs.BoolVar(&c.wordBank, "word-bank", c.wordBank, "list the answers alphabetically under the empty puzzle, for fill-in puzzles")
+	fs.BoolVar(&c.crop, "crop", false, "cut the grid down to the rectangle holding the words")
+	fs.BoolVar(&c.rtl, "rtl", false, "mirror the puzzle for a right-to-left script such as Hebrew or Arabic: across answers run leftwards, numbered from the top-right")
+	fs.IntVar(&c.margin, "margin", 0, "with -crop, keep this many empty rows and columns around the words")
+	fs.StringVar(&c.imports, "import", "", "render or convert an existing puzzle file (.ipuz or .xd) instead of generating one")
+	fs.BoolVar(&c.extend, "extend", false, "with -import, add the words of the word list to the imported puzzle")
+	fs.StringVar(&c.scanDict, "accidental", "", "word list `file` to scan the finished grid against for words spelled by accident (builtin for the built-in list of -lang)")
+	fs.IntVar(&c.minLen, "min-len", 0, "drop words with fewer letters than this from the word list (0 = no limit)")
+	fs.IntVar(&c.maxLen, "max-len", 0, "drop words with more letters than this from the word list (0 = no limit)")
+	fs.IntVar(&c.maxWords, "max-words", 0, "use only the first this many words of each word list, after the length limits (0 = no limit)")
+	fs.StringVar(&c.blocklist, "blocklist", "", "word list `file` of answers never to use: listed words are dropped from the word list and the grid is scanned for them")
+	fs.StringVar(&c.freqFile, "freq", "", "word frequency list `file` (most common first, or \"word count\" lines) for the difficulty estimate")
+	fs.StringVar(&c.lock, "lock", "", "with -import, comma-separated answers to keep in place; the rest of the grid is regenerated from the word list")
+	fs.StringVar(&c.highlight, "highlight", "", "comma-separated answers to mark in exports, as WORD for a nina or WORD=colour")
+	fs.StringVar(&c.meta.Title, "title", "", "puzzle title for the output and exports")
+	fs.StringVar(&c.meta.Author, "author", "", "author (setter) for the output and exports")
+	fs.StringVar(&c.meta.Copyright, "copyright", "", "copyright line for the output and exports")
+	fs.StringVar(&c.meta.Notes, "notes", "", "notes or instructions shown to solvers")
+	fs.StringVar(&c.circle, "circle", "", "comma-separated answers or row:col cells (from 1) to circle")
+	fs.StringVar(&c.shade, "shade", "", "comma-separated answers or row:col cells (from 1) to shade")
+	fs.StringVar(&c.archive, "archive", "", "also keep every puzzle in this store: a directory, or kind:place with kind "+strings.Join(storeKindNames(), " or "))
+	fs.StringVar(&c.upload, "upload", "", "also upload every puzzle written to this object-store `key` template, e.g. s3://bucket/{date}/{n}{ext} (see upload.go)")
+	fs.StringVar(&c.post, "post", "", "also post every puzzle to this Slack or Discord incoming webhook `URL`")
+	fs.StringVar(&c.export, "export", "", "write the puzzle in an interchange format instead of text: "+strings.Join(exportFormats(), " or "))
+	fs.StringVar(&c.format, "format", "text", "grid style: "+strings.Join(gridFormats(), " or "))
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if c.config != "" {
+		if err := c.loadConfig(fs); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, err
+		}
+	}
+	if _, err := lookupLanguage(c.lang); err != nil {
+		fmt.Fprintf(os.Stderr, "-lang: %v\n", err)
+		return nil, err
+	}
+	// word searches are written as text or NDJSON only
+	if c.mode == "wordsearch" && c.export != "" && c.export != "ndjson" {
+		err := fmt.Errorf("-export %s is not available in -mode wordsearch (want ndjson)", c.export)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, err
+	}
+	// an NDJSON batch is meant to be streamed, so it stays on stdout
+	if c.count > 1 && c.out == "" && name != "book" && c.export != "ndjson" {
+		c.out = "puzzle"
+	}
+	return c, nil
+}
+
+// wordPools returns the word lists to rotate through: the -wordfile lists if
+// given, the built-in list otherwise.
+func (c *cli) wordPools() ([][]string, error) {
+	if c.wordFiles == "" {
+		return [][]string{c.words}, nil
+	}
+	var pools [][]string
+	for _, path := range strings.Split(c.wordFiles, ",") {
+		pool, err := readWordFile(c.wordListPath(path))
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+// accidental returns the dictionary of the -accidental scan, or nil.
+func (c *cli) accidental() (*dictionary, error) {
+	if c.scanDict == "" {
+		return nil, nil
+	}
+	words, err := readWordFile(c.wordListPath(c.scanDict))
+	if err != nil {
+		return nil, err
+	}
+	return newDictionary(words), nil
+}
+
+// blocked returns the -blocklist, or nil.
+func (c *cli) blocked() (*dictionary, error) {
+	if c.blocklist == "" {
+		return nil, nil
+	}
+	return readBlocklist(c.wordListPath(c.blocklist))
+}
+
+// wordListPath returns path, naming the built-in list of the -lang profile
+// if it is BUILTIN_WORDLIST.
+func (c *cli) wordListPath(path string) string {
+	if path == BUILTIN_WORDLIST && c.lang != "" {
+		return BUILTIN_WORDLIST + ":" + c.lang
+	}
+	return path
+}
+
+// folds returns the accent folding of answers: nil unless foldAccents is
+// set, otherwise that of the -lang profile.
+func (c *cli) folds() map[rune]string {
+	return Options{FoldAccents: c.foldAccents, Language: c.lang}.folds()
+}
+
+// screen drops the words on block from pools, logging each, and fails if a
+// pool is left empty.
+func screen(pools [][]string, block *dictionary, logger *slog.Logger) ([][]string, error) {
+	if block == nil {
+		return pools, nil
+	}
+	out := make([][]string, len(pools))
+	for i, pool := range pools {
+		for _, w := range pool {
+			if onBlocklist(w, block) {
+				logger.Warn("word is on the blocklist, dropped", "word", w)
+				continue
+			}
+			out[i] = append(out[i], w)
+		}
+		if len(out[i]) == 0 {
+			return nil, errors.New("every word of the list is on the blocklist")
+		}
+	}
+	return out, nil
+}
+
+// PRUNE_REPORT_WORDS is how many dropped words the pruning report names.
+const PRUNE_REPORT_WORDS = 10
+
+// prune applies -min-len, -max-len and -max-words to pools, logging how many
+// words each dropped, and fails if a pool is left empty.
+func (c *cli) prune(pools [][]string, logger *slog.Logger) ([][]string, error) {
+	if c.minLen <= 0 && c.maxLen <= 0 && c.maxWords <= 0 {
+		return pools, nil
+	}
+	out := make([][]string, len(pools))
+	for i, pool := range pools {
+		var short, long []string
+		for _, w := range pool {
+			n := len([]rune(gridForm(w, c.folds())))
+			switch {
+			case c.minLen > 0 && n < c.minLen:
+				short = append(short, w)
+			case c.maxLen > 0 && n > c.maxLen:
+				long = append(long, w)
+			default:
+				out[i] = append(out[i], w)
+			}
+		}
+		var extra []string
+		if c.maxWords > 0 && len(out[i]) > c.maxWords {
+			out[i], extra = out[i][:c.maxWords], out[i][c.maxWords:]
+		}
+		for _, dropped := range []struct {
+			reason string
+			words  []string
+		}{{"shorter than -min-len", short}, {"longer than -max-len", long}, {"over -max-words", extra}} {
+			if len(dropped.words) > 0 {
+				logger.Info("words dropped", "list", i+1, "reason", dropped.reason, "count", len(dropped.words),
+					"words", strings.Join(dropped.words[:min(len(dropped.words), PRUNE_REPORT_WORDS)], ","))
+			}
+		}
+		if len(out[i]) == 0 {
+			return nil, fmt.Errorf("word list %d has no words within the length limits", i+1)
+		}
+	}
+	return out, nil
+}
+
+// frequencies returns the -freq list for rating difficulty, or nil.
+func (c *cli) frequencies() (*frequencies, error) {
+	if c.freqFile == "" {
+		return nil, nil
+	}
+	return readFrequencies(c.freqFile)
+}
+
+// clues returns the clue provider for the run: the -config hints, the -clues
+// files, then the -llm-endpoint for words they lack. nil if none is given.
+func (c *cli) clues() (ClueProvider, error) {
+	var chain chainClues
+	if c.configClues != nil {
+		chain = append(chain, c.configClues)
+	}
+	if c.clueFiles != "" {
+		files, err := readClueFiles(strings.Split(c.clueFiles, ","))
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, files)
+	}
+	if c.llmEndpoint != "" {
+		llm, err := newLLMClues(c.llmEndpoint, c.llmModel, os.Getenv(c.llmKeyEnv), c.clueDifficulty, c.llmRate, c.llmCache)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, llm)
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// grid returns the renderer selected with -format.
+func (c *cli) grid() (gridRenderer, error) {
+	if r, ok := gridRenderers[c.format]; ok {
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown -format %q (want %s)", c.format, strings.Join(gridFormats(), " or "))
+}
+
+// color reports whether stdout output should use ANSI colors: not with
+// -no-color or NO_COLOR set, and only when stdout is a terminal.
+func (c *cli) color() bool {
+	if c.noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "doctor" {
+		c, err := parseCLI("doctor", args[1:])
+		if err != nil {
+			os.Exit(EXIT_USAGE)
+		}
+		os.Exit(runDoctor(c, os.Stdout))
+	}
+	if len(args) > 0 && args[0] == "book" {
+		c, err := parseCLI("book", args[1:])
+		if err != nil {
+			os.Exit(EXIT_USAGE)
+		}
+		logger, err := newLogger(c.logFormat, c.quiet, c.verbose)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(EXIT_USAGE)
+		}
+		finish(logger, runBook(c, logger))
+	}
+	if len(args) > 0 && args[0] == "solve" {
+		os.Exit(runSolve(args[1:], os.Stdout))
+	}
+	if len(args) > 0 && args[0] == "suggest" {
+		os.Exit(runSuggest(args[1:], os.Stdout))
+	}
+	if len(args) > 0 && args[0] == "anagram" {
+		os.Exit(runAnagram(args[1:], os.Stdout))
+	}
+	if len(args) > 0 && args[0] == "match" {
+		os.Exit(runMatch(args[1:], os.Stdout))
+	}
+
+	c, err := parseCLI("crossword", args)
+	if err != nil {
+		os.Exit(EXIT_USAGE)
+	}
+	logger, err := newLogger(c.logFormat, c.quiet, c.verbose)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(EXIT_USAGE)
+	}
+	grid, err := c.grid()
+	if err != nil {
+		fail(logger, EXIT_USAGE, "invalid -format", err)
+	}
+	if err := c.exporter(); err != nil {
+		fail(logger, EXIT_USAGE, "invalid -export", err)
+	}
+	pools, err := c.wordPools()
+	if err != nil {
+		fail(logger, EXIT_USAGE, "cannot read word list", err)
+	}
+
+	clues, err := c.clues()
+	if err != nil {
+		fail(logger, EXIT_USAGE, "cannot read clues", err)
+	}
+	scan, err := c.accidental()
+	if err != nil {
+		fail(logger, EXIT_USAGE, "cannot read the -accidental word list", err)
+	}
+	block, err := c.blocked()
+	if err != nil {
+		fail(logger, EXIT_USAGE, "cannot read the -blocklist", err)
+	}
+	if pools, err = screen(pools, block, logger); err != nil {
+		fail(logger, EXIT_USAGE, "no words left", err)
+	}
+	if pools, err = c.prune(pools, logger); err != nil {
+		fail(logger, EXIT_USAGE, "no words left", err)
+	}
+	freq, err := c.frequencies()
+	if err != nil {
+		fail(logger, EXIT_USAGE, "cannot read the -freq list", err)
+	}
+
+	if err := checkRestart(c.restart); err != nil {
+		fail(logger, EXIT_USAGE, "invalid -restart", fmt.Errorf("%v (want %s)", err, strings.Join(restartNames(), ", ")))
+	}
+	if err := checkStrategy(c.strategy); err != nil {
+		fail(logger, EXIT_USAGE, "invalid -strategy", fmt.Errorf("%v (want %s)", err, strings.Join(strategyNames(), ", ")))
+	}
+	if _, err := c.placementDirections(); err != nil {
+		fail(logger, EXIT_USAGE, "invalid -directions", err)
+	}
+
+	switch c.mode {
+	case "crossword", "codeword", "krisskross":
+	case "wordsearch":
+		finish(logger, runWordSearch(c, logger, pools, scan, block))
+	default:
+		fail(logger, EXIT_USAGE, "invalid -mode", fmt.Errorf("unknown -mode %q (want crossword, codeword, krisskross or wordsearch)", c.mode))
+	}
+
+	var archive Store
+	if c.archive != "" {
+		if archive, err = openStore(c.archive); err != nil {
+			fail(logger, EXIT_USAGE, "cannot open the -archive", err)
+		}
+	}
+
+	var up *uploader
+	if c.upload != "" {
+		if up, err = newUploader(c.upload); err != nil {
+			fail(logger, EXIT_USAGE, "invalid -upload", err)
+		}
+	}
+
+	var po *poster
+	if c.post != "" {
+		if po, err = newPoster(c.post); err != nil {
+			fail(logger, EXIT_USAGE, "invalid -post", err)
+		}
+	}
+
+	var imported *Puzzle
+	if c.imports != "" {
+		if imported, err = readPuzzleFile(c.imports); err != nil {
+			fail(logger, EXIT_USAGE, "cannot import puzzle", err)
+		}
+		c.count = 1
+	}
+
+	// -top runs a single search and writes its alternatives as the batch
+	var alternatives []*Puzzle
+	if c.top > 1 && imported == nil {
+		if c.count > 1 || c.autoSize {
+			fail(logger, EXIT_USAGE, "invalid -top", errors.New("-top cannot be combined with -count or -auto-size"))
+		}
+		if c.strategy != "" && c.strategy != "dfs" {
+			fail(logger, EXIT_USAGE, "invalid -top", errors.New("-top needs the dfs -strategy"))
+		}
+		opts := c.options(logger)
+		if !c.quiet {
+			opts.Progress = &barProgress{total: c.maxIter}
+		}
+		alternatives = generateTop(pools[0], c.top, opts, newRand())
+		if opts.Stats != nil {
+			writeStats(os.Stderr, opts.Stats)
+		}
+		if opts.Trace != nil && len(alternatives) > 0 {
+			writeTrace(os.Stderr, opts.Trace, alternatives[0])
+		}
+		if len(alternatives) < c.top {
+			logger.Info("fewer distinct layouts found than asked for", "found", len(alternatives), "asked", c.top)
+		}
+		c.count = len(alternatives)
+		if c.count == 0 {
+			fail(logger, EXIT_NO_PUZZLE, "no valid crossword produced", errors.New("the search placed no words"))
+		}
+	}
+
+	code, below := EXIT_OK, false
+	seen := make(map[string]bool)
+	for i := 0; i < c.count; i++ {
+		opts := c.options(logger)
+		if !c.quiet && alternatives == nil {
+			opts.Progress = &barProgress{total: c.maxIter}
+		}
+		if c.dot != "" && i == 0 && imported == nil && alternatives == nil {
+			opts.Tree = &SearchTree{Limit: c.dotLimit}
+		}
+		if c.replay != "" && i == 0 && imported == nil && alternatives == nil {
+			opts.Replay = &Replay{}
+		}
+
+		// regenerate a few times if the pool produced a grid we already have,
+		// possibly rotated or reflected
+		best := imported
+		switch {
+		case alternatives != nil:
+			best = alternatives[i]
+		case imported != nil && c.lock != "":
+			if best, err = regenerate(imported, strings.Split(c.lock, ","), pools[0], opts); err != nil {
+				fail(logger, EXIT_USAGE, "cannot lock words", err)
+			}
+		case imported != nil && c.extend:
+			best = extendPuzzle(imported, pools[0], opts)
+		}
+		for attempt := 0; imported == nil && alternatives == nil && attempt < BATCH_RETRIES; attempt++ {
+			if c.autoSize {
+				best = generateAutoSize(pools[i%len(pools)], AUTO_SIZE_MAX, opts)
+			} else {
+				best = generate(pools[i%len(pools)], opts)
+			}
+			if best == nil || !seen[best.CanonicalHash()] {
+				break
+			}
+			logger.Info("duplicate puzzle, regenerating", "puzzle", i+1)
+			opts.Progress = nil
+		}
+		if opts.Stats != nil && alternatives == nil {
+			writeStats(os.Stderr, opts.Stats)
+		}
+		if opts.Trace != nil && alternatives == nil {
+			writeTrace(os.Stderr, opts.Trace, best)
+		}
+		if opts.Tree != nil {
+			if err := writeFile(c.dot, opts.Tree.writeDOT); err != nil {
+				logger.Error("cannot write the search tree", "err", err)
+			}
+		}
+		if opts.Replay != nil && best != nil {
+			write := opts.Replay.writeJSON
+			if strings.HasSuffix(strings.ToLower(c.replay), ".gif") {
+				write = opts.Replay.writeGIF
+			}
+			if err := writeFile(c.replay, write); err != nil {
+				logger.Error("cannot write the replay", "err", err)
+			}
+		}
+
+		// a search that placed nothing still returns its empty grid
+		if best == nil || imported == nil && len(best.entries()) == 0 {
+			logger.Error("no valid crossword produced", "puzzle", i+1)
+			code = EXIT_NO_PUZZLE
+			continue
+		}
+		if seen[best.CanonicalHash()] {
+			logger.Warn("could not find a distinct puzzle", "puzzle", i+1)
+		}
+		seen[best.CanonicalHash()] = true
+		for _, v := range Validate(best, nil) {
+			if v.Kind == VIOLATION_DISCONNECTED && c.allowIslands {
+				continue
+			}
+			logger.Warn("grid breaks a rule", "puzzle", i+1, "problem", v)
+		}
+		if scan != nil {
+			for _, v := range best.Accidental(scan) {
+				logger.Warn("grid spells a word by accident", "puzzle", i+1, "problem", v)
+			}
+		}
+		if block != nil {
+			for _, v := range best.Accidental(block) {
+				logger.Warn("grid spells a blocked word", "puzzle", i+1, "problem", v)
+			}
+		}
+		if c.autoSize {
+			logger.Info("grid size chosen", "puzzle", i+1, "size", best.Rows)
+		}
+		// an imported puzzle was not made to these requirements
+		if imported == nil && best.Intersections() < c.reqIntersections {
+			logger.Warn("intersection requirement not met", "puzzle", i+1, "intersections", best.Intersections(), "required", c.reqIntersections)
+			below = true
+		}
+		if imported == nil && c.minDensity > 0 && best.Density() < c.minDensity {
+			logger.Warn("density requirement not met", "puzzle", i+1, "density", best.Density(), "required", c.minDensity)
+			below = true
+		}
+		if imported == nil && c.maxEmpty > 0 && best.BoundingEmpty() > c.maxEmpty {
+			logger.Warn("too many empty cells", "puzzle", i+1, "empty", best.BoundingEmpty(), "allowed", c.maxEmpty)
+			below = true
+		}
+
+		if c.crop {
+			best = best.Crop(c.margin)
+		}
+		if c.rtl {
+			best = best.RightToLeft()
+		}
+		best.Meta = c.metadata(best.Meta)
+		difficulty := best.Rate(freq)
+		if clues != nil {
+			if err := best.setClues(clues); err != nil {
+				logger.Warn("clue lookup failed", "puzzle", i+1, "err", err)
+			}
+		}
+		for _, ref := range best.brokenRefs() {
+			logger.Warn("clue refers to an answer not in the puzzle", "puzzle", i+1, "answer", ref)
+		}
+		if c.highlight != "" {
+			for _, item := range strings.Split(c.highlight, ",") {
+				word, colour, _ := strings.Cut(item, "=")
+				e, ok := best.Find(strings.TrimSpace(word))
+				if !ok {
+					logger.Warn("highlighted word is not in the puzzle", "puzzle", i+1, "word", word)
+					continue
+				}
+				best.Highlight(getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word), strings.TrimSpace(colour))
+			}
+		}
+		for _, marking := range []struct {
+			items string
+			kind  int
+		}{{c.circle, MARK_CIRCLE}, {c.shade, MARK_SHADE}} {
+			if marking.items == "" {
+				continue
+			}
+			for _, item := range strings.Split(marking.items, ",") {
+				cells, err := best.cellsOf(item)
+				if err != nil {
+					logger.Warn("cannot mark cells", "puzzle", i+1, "err", err)
+					continue
+				}
+				best.Mark(cells, marking.kind)
+			}
+		}
+		for _, e := range best.entries() {
+			if e.Clue == "" && clues != nil {
+				logger.Info("no clue found", "puzzle", i+1, "word", e.Display)
+			}
+			if n := best.Crossings(e); imported == nil && n < c.minCrossings {
+				logger.Warn("word crosses too few others", "puzzle", i+1, "word", e.Display, "crossings", n, "required", c.minCrossings)
+				below = true
+			}
+		}
+
+		ext := ".txt"
+		if c.export != "" {
+			ext = exporters[c.export].ext
+		}
+		write := func(w io.Writer, color bool) error {
+			switch {
+			case c.export != "":
+				return exporters[c.export].write(w, best)
+			case c.mode == "codeword":
+				writeCodeword(w, newCodeword(best, CODEWORD_STARTERS), c.showKey)
+			case c.mode == "krisskross":
+				writeKrissKross(w, best, grid, c.showKey, color)
+			default:
+				writePuzzle(w, best, grid, c.showBlank, c.wordBank, color)
+			}
+			return nil
+		}
+		name, err := c.emit(i, ext, write)
+		if err != nil {
+			fail(logger, EXIT_FAILURE, "cannot write puzzle", err)
+		}
+		if name != "" {
+			logger.Info("puzzle written", "file", name, "intersections", best.Intersections(), "difficulty", difficulty.Level)
+		}
+		if up != nil {
+			var buf bytes.Buffer
+			if err := write(&buf, false); err != nil {
+				logger.Warn("cannot upload puzzle", "puzzle", i+1, "err", err)
+			} else if location, err := up.upload(up.expandKey(best, i+1, ext), buf.Bytes()); err != nil {
+				logger.Warn("cannot upload puzzle", "puzzle", i+1, "err", err)
+			} else {
+				logger.Info("puzzle uploaded", "puzzle", i+1, "url", location)
+			}
+		}
+		if po != nil {
+			if err := po.post(best); err != nil {
+				logger.Warn("cannot post puzzle", "puzzle", i+1, "err", err)
+			} else {
+				logger.Info("puzzle posted", "puzzle", i+1)
+			}
+		}
+		if archive != nil {
+			if id, err := archive.Put(best); err != nil {
+				logger.Warn("cannot archive puzzle", "puzzle", i+1, "err", err)
+			} else {
+				logger.Info("puzzle archived", "puzzle", i+1, "id", id)
+			}
+		}
+	}
+	if code == EXIT_OK && below {
+		code = EXIT_BELOW
+	}
+	finish(logger, code)
+}
+
+// metadata returns base with the fields given on the command line replaced.
+func (c *cli) metadata(base Metadata) Metadata {
+	for _, field := range []struct{ from, to *string }{
+		{&c.meta.Title, &base.Title},
+		{&c.meta.Author, &base.Author},
+		{&c.meta.Copyright, &base.Copyright},
+		{&c.meta.Notes, &base.Notes},
+	} {
+		if *field.from != "" {
+			*field.to = *field.from
+		}
+	}
+	return base
+}
+
+// placementDirections parses -directions; nil leaves the mode's default.
+// Crossword modes need directions on two axes.
+func (c *cli) placementDirections() ([]int, error) {
+	if c.directions == "" {
+		return nil, nil
+	}
+	dirs, err := parseDirections(c.directions)
+	if err != nil {
+		return nil, err
+	}
+	if c.mode != "wordsearch" {
+		if err := checkDirections(dirs); err != nil {
+			return nil, err
+		}
+	}
+	return dirs, nil
+}
+
+// options returns the generation options of the run, without progress.
+// -directions must have been checked with placementDirections.
+func (c *cli) options(logger *slog.Logger) Options {
+	dirs, _ := c.placementDirections()
+	var stats *GenerationStats
+	if c.stats {
+		stats = &GenerationStats{}
+	}
+	var trace PlacementTrace
+	if c.explain {
+		trace = PlacementTrace{}
+	}
+	return Options{
+		GridSize:         c.gridSize,
+		ReqIntersections: c.reqIntersections,
+		MaxIter:          c.maxIter,
+		MaxDepth:         c.maxDepth,
+		Restart:          c.restart,
+		RestartUnit:      c.restartUnit,
+		Strategy:         c.strategy,
+		MemoMB:           c.memoMB,
+		MinDensity:       c.minDensity,
+		MaxEmpty:         c.maxEmpty,
+		MinCrossings:     c.minCrossings,
+		AllowIslands:     c.allowIslands,
+		MostConstrained:  c.mostConstrained,
+		Directions:       dirs,
+		FoldAccents:      c.foldAccents,
+		Language:         c.lang,
+		Stats:            stats,
+		Trace:            trace,
+		Logger:           logger,
+	}
+}
+
+// emit writes puzzle i of the run to stdout, or to its numbered file with
+// -out and the extension ext. Returns the file name, empty for stdout.
+func (c *cli) emit(i int, ext string, write func(w io.Writer, color bool) error) (string, error) {
+	if c.out == "" {
+		return "", write(os.Stdout, c.color())
+	}
+	name := fmt.Sprintf("%s-%0*d%s", c.out, len(strconv.Itoa(c.count)), i+1, ext)
+	f, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if err := write(f, false); err != nil {
+		f.Close()
+		return "", err
+	}
+	return name, f.Close()
+}
+
+// writeFile creates the file name and writes it with write.
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exporter checks the -export format name.
+func (c *cli) exporter() error {
+	if _, ok := exporters[c.export]; ok || c.export == "" {
+		return nil
+	}
+	return fmt.Errorf("unknown -export %q (want %s)", c.export, strings.Join(exportFormats(), " or "))
+}
+
+// runWordSearch is main for -mode wordsearch. Returns the exit code.
+// Fills spelling a word of block are redrawn.
+func runWordSearch(c *cli, logger *slog.Logger, pools [][]string, scan, block *dictionary) int {
+	code := EXIT_OK
+	seen := make(map[string]bool)
+	dirs, _ := c.placementDirections()
+	for i := 0; i < c.count; i++ {
+		opts := Options{
+			GridSize:    c.gridSize,
+			MaxIter:     c.maxIter,
+			Directions:  dirs,
+			FoldAccents: c.foldAccents,
+			Language:    c.lang,
+			Logger:      logger,
+		}
+		ws := generateWordSearch(pools[i%len(pools)], opts)
+		for attempt := 0; ws != nil && block != nil && attempt < BATCH_RETRIES; attempt++ {
+			blocked := ws.Accidental(block)
+			if len(blocked) == 0 {
+				break
+			}
+			logger.Info("fill spells a blocked word, refilling", "puzzle", i+1, "problem", blocked[0])
+			ws = generateWordSearch(pools[i%len(pools)], opts)
+		}
+		if ws == nil {
+			logger.Error("words do not fit in the word search", "puzzle", i+1, "size", c.gridSize)
+			code = EXIT_NO_PUZZLE
+			continue
+		}
+		// random fill makes an identical grid all but impossible, but batches still check
+		if seen[ws.String()] {
+			logger.Warn("could not find a distinct puzzle", "puzzle", i+1)
+		}
+		seen[ws.String()] = true
+		if scan != nil {
+			for _, v := range ws.Accidental(scan) {
+				logger.Warn("fill spells a word by accident", "puzzle", i+1, "problem", v)
+			}
+		}
+		if block != nil {
+			for _, v := range ws.Accidental(block) {
+				logger.Warn("fill spells a blocked word", "puzzle", i+1, "problem", v)
+			}
+		}
+
+		ext := ".txt"
+		if c.export == "ndjson" {
+			ext = ".json"
+		}
+		name, err := c.emit(i, ext, func(w io.Writer, color bool) error {
+			if c.export == "ndjson" {
+				return writeJSONLine(w, ws)
+			}
+			writeWordSearch(w, ws, c.showKey)
+			return nil
+		})
+		if err != nil {
+			logger.Error("cannot write puzzle", "err", err)
+			return EXIT_FAILURE
+		}
+		if name != "" {
+			logger.Info("puzzle written", "file", name)
+		}
+	}
+	return code
+}
+
+// Exit codes, for scripts to branch on. A run that cannot produce one of its
+// puzzles exits EXIT_NO_PUZZLE even if others were written.
+const (
+	EXIT_OK        = 0
+	EXIT_FAILURE   = 1 // a file could not be written, or doctor found a problem
+	EXIT_USAGE     = 2 // bad flags or unreadable input
+	EXIT_BELOW     = 3 // every puzzle was written, but some miss the requirements
+	EXIT_NO_PUZZLE = 4 // some puzzle could not be produced at all
+)
+
+// exitOutcomes names the exit codes in the log record a failed run ends
+// with, so -log-format json gives scripts an error object to parse.
+var exitOutcomes = map[int]string{
+	EXIT_FAILURE:   "failure",
+	EXIT_USAGE:     "invalid-input",
+	EXIT_BELOW:     "below-requirements",
+	EXIT_NO_PUZZLE: "no-puzzle",
+}
+
+// fail logs err and exits with code.
+func fail(logger *slog.Logger, code int, msg string, err error) {
+	logger.Error(msg, "err", err, "exit", code, "outcome", exitOutcomes[code])
+	os.Exit(code)
+}
+
+// finish exits with code, logging the outcome first unless the run
+// succeeded.
+func finish(logger *slog.Logger, code int) {
+	switch code {
+	case EXIT_OK:
+	case EXIT_BELOW:
+		logger.Warn("run finished", "exit", code, "outcome", exitOutcomes[code])
+	default:
+		logger.Error("run finished", "exit", code, "outcome", exitOutcomes[code])
+	}
+	os.Exit(code)
+}
+
+// BATCH_RETRIES bounds how often a batch puzzle is regenerated when it comes
+// out identical to an earlier one.
+const BATCH_RETRIES = 5
+
+// newLogger builds the stderr logger for the CLI. -quiet wins over -v.
+func newLogger(format string, quiet, verbose bool) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if verbose {
+		opts.Level = slog.LevelDebug
+	}
+	if quiet {
+		opts.Level = slog.LevelWarn
+	}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown -log-format %q (want text or json)", format)
+}
+
+// barProgress draws the terminal progress bar; it is only used by the CLI.
+// The bar is started on the first iteration so runs that never reach the
+// shuffle loop (minis, tiled grids) print nothing.
+type barProgress struct {
+	total int
+	bar   *pb.ProgressBar
+}
+
+func (b *barProgress) OnIteration(i, best int) {
+	if b.bar == nil {
+		b.bar = pb.StartNew(b.total)
+	}
+	b.bar.SetCurrent(int64(i + 1))
+}
+
+func (b *barProgress) OnDone() {
+	if b.bar != nil {
+		b.bar.Finish()
+	}
+}
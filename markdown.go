@@ -0,0 +1,92 @@
+// file: markdown.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The Markdown export is for wikis and puzzle archives kept on GitHub: the
+// empty grid as a table with the clue numbers in their cells, the clue
+// lists, and the solution folded away in a <details> section that renders
+// collapsed on GitHub.
+
+// mdEscape escapes the characters that would end a table cell or start
+// emphasis in text written into Markdown.
+func mdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "<", "&lt;").Replace(s)
+}
+
+// writeMarkdown writes p as a Markdown document.
+func writeMarkdown(w io.Writer, p *Puzzle) error {
+	m := p.Meta
+	if m.Title != "" {
+		fmt.Fprintf(w, "# %s\n\n", mdEscape(m.Title))
+	}
+	if m.Author != "" {
+		fmt.Fprintf(w, "*by %s*\n\n", mdEscape(m.Author))
+	}
+	if m.Notes != "" {
+		fmt.Fprintf(w, "%s\n\n", mdEscape(m.Notes))
+	}
+
+	numbers := make(map[Pos]int)
+	for _, e := range p.entries() {
+		numbers[Pos{e.Row, e.Col}] = e.Number
+	}
+	// tables need a header row; an empty one keeps the grid square
+	fmt.Fprintln(w, "|"+strings.Repeat("   |", p.Cols))
+	fmt.Fprintln(w, "|"+strings.Repeat(":-:|", p.Cols))
+	for r := 0; r < p.Rows; r++ {
+		cells := make([]string, p.Cols)
+		for c := 0; c < p.Cols; c++ {
+			switch {
+			case p.Cell(r, c) == '#':
+				cells[c] = "⬛"
+			case numbers[Pos{r, c}] != 0:
+				cells[c] = fmt.Sprintf("<sup>%d</sup>", numbers[Pos{r, c}])
+			default:
+				cells[c] = " "
+			}
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+
+	for _, list := range []struct {
+		title   string
+		entries []Entry
+	}{{"Across", p.AcrossEntries()}, {"Down", p.DownEntries()}} {
+		fmt.Fprintf(w, "\n## %s\n\n", list.title)
+		for _, e := range list.entries {
+			clue := p.ClueText(e)
+			if e.Clue == "" {
+				clue = "(" + e.lengths() + ")"
+			}
+			if dir := otherDirection(p, e); dir != "" {
+				clue += " [" + dir + "]"
+			}
+			fmt.Fprintf(w, "- **%d** %s\n", e.Number, mdEscape(clue))
+		}
+	}
+
+	fmt.Fprintln(w, "\n<details>\n<summary>Solution</summary>\n\n```")
+	printGrid(w, p, false, false)
+	fmt.Fprintln(w, "```")
+	fmt.Fprintln(w)
+	for _, list := range []struct {
+		title   string
+		entries []Entry
+	}{{"Across", p.AcrossEntries()}, {"Down", p.DownEntries()}} {
+		answers := make([]string, len(list.entries))
+		for i, e := range list.entries {
+			answers[i] = fmt.Sprintf("%d %s", e.Number, mdEscape(e.Display))
+		}
+		fmt.Fprintf(w, "**%s:** %s\n\n", list.title, strings.Join(answers, ", "))
+	}
+	fmt.Fprintln(w, "</details>")
+	if m.Copyright != "" {
+		fmt.Fprintf(w, "\n© %s\n", mdEscape(strings.TrimPrefix(m.Copyright, "© ")))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// file: marks.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Theme puzzles often circle or shade some cells, e.g. the letters of a
+// hidden reveal. Marks are kept per cell as a bitmask, like bars.
+const (
+	MARK_CIRCLE = 1 << iota
+	MARK_SHADE
+)
+
+// SHADE_COLOUR is the colour shaded cells get in exports that need one.
+const SHADE_COLOUR = "#D3D3D3"
+
+// Mark circles or shades (MARK_CIRCLE, MARK_SHADE) cells.
+func (p *Puzzle) Mark(cells []Pos, kind int) {
+	if p.marks == nil {
+		p.marks = make(map[Pos]int)
+	}
+	for _, loc := range cells {
+		p.marks[loc] |= kind
+	}
+}
+
+// Marked reports whether loc carries the mark kind.
+func (p *Puzzle) Marked(loc Pos, kind int) bool {
+	return p.marks[loc]&kind != 0
+}
+
+// markedCells returns the cells with the mark kind in reading order.
+func (p *Puzzle) markedCells(kind int) []Pos {
+	var cells []Pos
+	for loc, marks := range p.marks {
+		if marks&kind != 0 {
+			cells = append(cells, loc)
+		}
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].R != cells[j].R {
+			return cells[i].R < cells[j].R
+		}
+		return cells[i].C < cells[j].C
+	})
+	return cells
+}
+
+// cellsOf returns the cells named by item: every cell of an answer (matched
+// as by Find), or a single cell given as "row:col", counted from 1.
+func (p *Puzzle) cellsOf(item string) ([]Pos, error) {
+	item = strings.TrimSpace(item)
+	var r, c int
+	if n, _ := fmt.Sscanf(item, "%d:%d", &r, &c); n == 2 {
+		if r < 1 || c < 1 || r > p.Rows || c > p.Cols || p.Cell(r-1, c-1) == '#' {
+			return nil, fmt.Errorf("cell %s holds no letter", item)
+		}
+		return []Pos{{r - 1, c - 1}}, nil
+	}
+	e, ok := p.Find(item)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in the puzzle", item)
+	}
+	return getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word), nil
+}
@@ -0,0 +1,77 @@
+// file: memo.go
+package main
+
+import "hash/fnv"
+
+// Shuffles of the same word list often reach the same partial grid with the
+// same words left over, and a level that failed once fails again. failMemo
+// remembers such levels by a 64-bit signature of the remaining words, the
+// direction and the grid, so later searches skip them. The grid part is a
+// Zobrist-style hash kept up to date by addToGrid and removeFromGrid.
+//
+// Only levels that ran out of words and heads are recorded; a search cut
+// short by its depth budget proves nothing.
+
+// MEMO_ENTRY_BYTES is the rough cost of one remembered signature in a Go map,
+// used to turn a memory budget into a number of entries.
+const MEMO_ENTRY_BYTES = 40
+
+// failMemo is a set of failed level signatures with a size limit. When it
+// fills up it starts over, which favours the dead ends of recent shuffles.
+type failMemo struct {
+	failed map[uint64]bool
+	limit  int
+	words  map[string]uint64 // cached word hashes
+}
+
+// newFailMemo returns a memo using about megabytes of memory, or nil (no
+// memo) for a budget of zero or less.
+func newFailMemo(megabytes int) *failMemo {
+	if megabytes <= 0 {
+		return nil
+	}
+	return &failMemo{failed: make(map[uint64]bool), limit: megabytes << 20 / MEMO_ENTRY_BYTES, words: make(map[string]uint64)}
+}
+
+// signature identifies the level placing words across the axis (see
+// directionAxes) of the word placed below it, on the grid described by index.
+// words is treated as a multiset.
+func (m *failMemo) signature(words []string, axis int, index *gridIndex) uint64 {
+	var sum uint64
+	for _, w := range words {
+		h, ok := m.words[w]
+		if !ok {
+			f := fnv.New64a()
+			f.Write([]byte(w))
+			h = mix64(f.Sum64())
+			m.words[w] = h
+		}
+		sum += h
+	}
+	return mix64(sum^uint64(axis+1)) ^ index.hash
+}
+
+func (m *failMemo) has(sig uint64) bool {
+	return m.failed[sig]
+}
+
+func (m *failMemo) add(sig uint64) {
+	if len(m.failed) >= m.limit {
+		clear(m.failed)
+	}
+	m.failed[sig] = true
+}
+
+// cellHash is the Zobrist key of letter r placed at loc in direction.
+func cellHash(loc Pos, r rune, direction int) uint64 {
+	return mix64(uint64(loc.R)<<44 ^ uint64(loc.C)<<24 ^ uint64(r)<<3 ^ uint64(direction))
+}
+
+// mix64 is the splitmix64 finalizer, spreading every input bit over the result.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
@@ -0,0 +1,215 @@
+// file: mini.go
+package main
+
+import (
+	"math/bits"
+	"math/rand"
+	"sort"
+)
+
+// Mini crosswords (5x5, 6x6) are filled densely: every letter cell belongs to
+// two entries. The freeform backtracker forbids touching words, so it can never
+// produce such grids; instead we enumerate symmetric block patterns and fill
+// their slots exhaustively from the word list.
+
+const (
+	MINI_MAX_SIZE  = 6 // grids up to this size take the mini fast path
+	MINI_MIN_ENTRY = 3 // shortest entry allowed in a mini
+)
+
+// generateMini tries every block pattern (fewest blocks first) and fills it
+// with distinct words from the list. maxSteps bounds the total number of word
+// trials across all patterns. Returns nil if no pattern could be filled.
+func generateMini(words []string, gridSize int, maxSteps int, display map[string]string, rng *rand.Rand) *Puzzle {
+	byLen := make(map[int][]string)
+	for _, w := range words {
+		byLen[len([]rune(w))] = append(byLen[len([]rune(w))], w)
+	}
+	// by length, not map order, so a seeded rng repeats the fill
+	lengths := make([]int, 0, len(byLen))
+	for n := range byLen {
+		lengths = append(lengths, n)
+	}
+	sort.Ints(lengths)
+	for _, n := range lengths {
+		list := byLen[n]
+		rng.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
+	}
+
+	steps := 0
+	for _, blocks := range miniTemplates(gridSize) {
+		slots := miniSlots(blocks, gridSize)
+		if !miniLengthsAvailable(slots, byLen) {
+			continue
+		}
+		grid := initGrid(gridSize)
+		assigned := make([]string, len(slots))
+		var order []int // slot indices in the order they were filled
+		if fillMini(slots, assigned, &order, byLen, grid, map[string]bool{}, &steps, maxSteps) {
+			classification := map[int][]Placement{HORIZONTAL: {}, VERTICAL: {}}
+			for n, i := range order {
+				head := slots[i].cells[0]
+				classification[slots[i].dir] = append(classification[slots[i].dir], Placement{Loc: gridSize*head.R + head.C, Word: assigned[i], Order: n})
+			}
+			return newPuzzle(gridSize, grid, classification, display)
+		}
+		if steps > maxSteps {
+			break
+		}
+	}
+	return nil
+}
+
+// miniTemplates lists block patterns with 180° rotational symmetry whose white
+// cells are connected and whose runs are all at least MINI_MIN_ENTRY long.
+// Patterns are ordered by number of blocks, so open grids are tried first.
+func miniTemplates(gridSize int) []map[Pos]bool {
+	var reps []Pos
+	for r := 0; r < gridSize; r++ {
+		for c := 0; c < gridSize; c++ {
+			if r*gridSize+c <= (gridSize-1-r)*gridSize+(gridSize-1-c) {
+				reps = append(reps, Pos{r, c})
+			}
+		}
+	}
+
+	var templates []map[Pos]bool
+	for mask := 0; mask < 1<<len(reps); mask++ {
+		if bits.OnesCount(uint(mask)) > gridSize*gridSize/6+1 {
+			continue // too many blocks even before mirroring
+		}
+		blocks := make(map[Pos]bool)
+		for i, p := range reps {
+			if mask&(1<<i) != 0 {
+				blocks[p] = true
+				blocks[Pos{gridSize - 1 - p.R, gridSize - 1 - p.C}] = true
+			}
+		}
+		if len(blocks) > gridSize*gridSize/3 {
+			continue
+		}
+		if miniRunsValid(blocks, gridSize) && miniConnected(blocks, gridSize) {
+			templates = append(templates, blocks)
+		}
+	}
+	sort.SliceStable(templates, func(i, j int) bool { return len(templates[i]) < len(templates[j]) })
+	return templates
+}
+
+// miniRunsValid reports whether every maximal run of white cells, in both
+// directions, is long enough to be an entry.
+func miniRunsValid(blocks map[Pos]bool, gridSize int) bool {
+	for _, s := range miniSlots(blocks, gridSize) {
+		if len(s.cells) < MINI_MIN_ENTRY {
+			return false
+		}
+	}
+	return true
+}
+
+func miniConnected(blocks map[Pos]bool, gridSize int) bool {
+	var start *Pos
+	white := 0
+	for r := 0; r < gridSize; r++ {
+		for c := 0; c < gridSize; c++ {
+			if !blocks[Pos{r, c}] {
+				white++
+				if start == nil {
+					start = &Pos{r, c}
+				}
+			}
+		}
+	}
+	if start == nil {
+		return false
+	}
+	seen := map[Pos]bool{*start: true}
+	stack := []Pos{*start}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, d := range []Pos{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			nb := Pos{p.R + d.R, p.C + d.C}
+			if nb.R < 0 || nb.R >= gridSize || nb.C < 0 || nb.C >= gridSize || blocks[nb] || seen[nb] {
+				continue
+			}
+			seen[nb] = true
+			stack = append(stack, nb)
+		}
+	}
+	return len(seen) == white
+}
+
+// slots returns the maximal white runs of the pattern (including runs too
+// short to be entries, which miniRunsValid rejects).
+func miniSlots(blocks map[Pos]bool, gridSize int) []slot {
+	return findSlots(gridSize, gridSize, func(p Pos) bool { return blocks[p] }, 1)
+}
+
+func miniLengthsAvailable(slots []slot, byLen map[int][]string) bool {
+	need := make(map[int]int)
+	for _, s := range slots {
+		need[len(s.cells)]++
+	}
+	for l, n := range need {
+		if len(byLen[l]) < n {
+			return false
+		}
+	}
+	return true
+}
+
+// fillMini assigns words to slots, always expanding the slot with the fewest
+// matching candidates next.
+func fillMini(slots []slot, assigned []string, order *[]int, byLen map[int][]string, grid map[Pos]rune,
+	used map[string]bool, steps *int, maxSteps int) bool {
+
+	best := -1
+	var bestCands []string
+	for i, s := range slots {
+		if assigned[i] != "" {
+			continue
+		}
+		var cands []string
+		for _, w := range byLen[len(s.cells)] {
+			if !used[w] && s.matches(w, grid) {
+				cands = append(cands, w)
+			}
+		}
+		if len(cands) == 0 {
+			return false
+		}
+		if best == -1 || len(cands) < len(bestCands) {
+			best, bestCands = i, cands
+		}
+	}
+	if best == -1 {
+		return true
+	}
+
+	s := slots[best]
+	for _, w := range bestCands {
+		*steps++
+		if *steps > maxSteps {
+			return false
+		}
+		previous := make([]rune, len(s.cells))
+		for idx, loc := range s.cells {
+			previous[idx] = grid[loc]
+			grid[loc] = []rune(w)[idx]
+		}
+		used[w] = true
+		assigned[best] = w
+		*order = append(*order, best)
+		if fillMini(slots, assigned, order, byLen, grid, used, steps, maxSteps) {
+			return true
+		}
+		*order = (*order)[:len(*order)-1]
+		assigned[best] = ""
+		used[w] = false
+		for idx, loc := range s.cells {
+			grid[loc] = previous[idx]
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+// file: options.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Go programs using the generator as a library call Generate, which starts
+// from the requirements.toml defaults and applies functional options in
+// order:
+//
+//	p, err := Generate(words, WithSize(15), WithIntersections(20), WithDirections(HORIZONTAL, VERTICAL))
+//
+// Options that cannot work for the words, such as more intersections than
+// their letters allow or a word longer than the grid, are reported up front
+// without searching.
+
+// ErrRequirementsNotMet is returned, wrapped, with the best puzzle Generate
+// found when it misses the required intersections, density or crossings.
+var ErrRequirementsNotMet = errors.New("requirements not met")
+
+// ErrNoPuzzle is returned when the search could not place any word.
+var ErrNoPuzzle = errors.New("no valid crossword produced")
+
+// Option sets one generation option for Generate.
+type Option func(*generateConfig)
+
+// generateConfig is what the Options of a Generate call build.
+type generateConfig struct {
+	Options
+	intersectionsSet bool // otherwise the requirement follows the grid size
+}
+
+// WithSize sets the grid to size by size cells (default DEFAULT_SIZE).
+func WithSize(size int) Option {
+	return func(c *generateConfig) { c.GridSize = size }
+}
+
+// WithIntersections sets the required intersections (default the grid size
+// minus 3).
+func WithIntersections(n int) Option {
+	return func(c *generateConfig) { c.ReqIntersections, c.intersectionsSet = n, true }
+}
+
+// WithIterations sets the number of shuffles tried (default
+// DEFAULT_ITERATIONS).
+func WithIterations(n int) Option {
+	return func(c *generateConfig) { c.MaxIter = n }
+}
+
+// WithDepth sets the placements tried per shuffle (default DEFAULT_DEPTH).
+func WithDepth(n int) Option {
+	return func(c *generateConfig) { c.MaxDepth = n }
+}
+
+// WithRestart sets the depth budget schedule, see Options.Restart, and the
+// budget of its first shuffle.
+func WithRestart(schedule string, unit int) Option {
+	return func(c *generateConfig) { c.Restart, c.RestartUnit = schedule, unit }
+}
+
+// WithStrategy picks the search algorithm by name, see Options.Strategy.
+func WithStrategy(name string) Option {
+	return func(c *generateConfig) { c.Strategy = name }
+}
+
+// WithDensity sets the minimum percentage of cells holding a letter.
+func WithDensity(percent float64) Option {
+	return func(c *generateConfig) { c.MinDensity = percent }
+}
+
+// WithMaxEmpty sets the most empty cells allowed inside the words' bounding
+// box.
+func WithMaxEmpty(n int) Option {
+	return func(c *generateConfig) { c.MaxEmpty = n }
+}
+
+// WithMinCrossings makes every word cross at least n others.
+func WithMinCrossings(n int) Option {
+	return func(c *generateConfig) { c.MinCrossings = n }
+}
+
+// WithDirections sets the directions words may run in, the first word
+// taking the first.
+func WithDirections(dirs ...int) Option {
+	return func(c *generateConfig) { c.Directions = dirs }
+}
+
+// WithFoldAccents places accented letters as their plain forms.
+func WithFoldAccents() Option {
+	return func(c *generateConfig) { c.FoldAccents = true }
+}
+
+// WithIslands accepts grids whose words form unconnected groups.
+func WithIslands() Option {
+	return func(c *generateConfig) { c.AllowIslands = true }
+}
+
+// WithMostConstrained tries the words with the fewest places to go first.
+func WithMostConstrained() Option {
+	return func(c *generateConfig) { c.MostConstrained = true }
+}
+
+// WithMemo gives the search mb megabytes to remember dead ends in (default
+// DEFAULT_MEMO_MB); 0 turns the memo off.
+func WithMemo(mb int) Option {
+	return func(c *generateConfig) { c.MemoMB = mb }
+}
+
+// WithProgress reports the search's progress to p.
+func WithProgress(p Progress) Option {
+	return func(c *generateConfig) { c.Progress = p }
+}
+
+// WithLanguage picks the language profile, see lang.go, for accent folding
+// and word search filler.
+func WithLanguage(name string) Option {
+	return func(c *generateConfig) { c.Language = name }
+}
+
+// WithLogger logs generation events to logger at debug level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *generateConfig) { c.Logger = logger }
+}
+
+// Generate lays out words with opts and returns the best puzzle found. The
+// error is a join of every problem with the options, ErrNoPuzzle, or
+// ErrRequirementsNotMet alongside the best-effort puzzle.
+func Generate(words []string, opts ...Option) (*Puzzle, error) {
+	c := generateConfig{Options: Options{
+		GridSize:    DEFAULT_SIZE,
+		MaxIter:     DEFAULT_ITERATIONS,
+		MaxDepth:    DEFAULT_DEPTH,
+		Restart:     "fixed",
+		RestartUnit: DEFAULT_RESTART_UNIT,
+		MemoMB:      DEFAULT_MEMO_MB,
+	}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if !c.intersectionsSet {
+		c.ReqIntersections = max(c.GridSize-3, 0)
+	}
+	if err := c.Validate(words); err != nil {
+		return nil, err
+	}
+	p := generate(words, c.Options)
+	if p == nil || len(p.entries()) == 0 {
+		return nil, ErrNoPuzzle
+	}
+	if !c.met(p) {
+		return p, fmt.Errorf("%w: %d intersections, %.0f%% filled", ErrRequirementsNotMet, p.Intersections(), p.Density())
+	}
+	return p, nil
+}
+
+// Validate reports every setting of opts that is out of range or cannot be
+// met by any layout of words, joined into one error; nil if there is none.
+func (opts Options) Validate(words []string) error {
+	var errs []error
+	bad := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	if len(words) == 0 {
+		bad("no words to place")
+	}
+	if opts.GridSize < 1 {
+		bad("grid size %d is not positive", opts.GridSize)
+	}
+	if opts.MaxIter < 1 {
+		bad("iterations %d is not positive", opts.MaxIter)
+	}
+	if opts.MaxDepth < 1 {
+		bad("depth %d is not positive", opts.MaxDepth)
+	}
+	if err := checkRestart(opts.Restart); err != nil {
+		errs = append(errs, err)
+	} else if opts.Restart != "" && opts.Restart != "fixed" && opts.RestartUnit < 1 {
+		bad("restart unit %d is not positive", opts.RestartUnit)
+	}
+	if err := checkStrategy(opts.Strategy); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkDirections(opts.Directions); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := lookupLanguage(opts.Language); err != nil {
+		errs = append(errs, err)
+	}
+	if opts.MinDensity < 0 || opts.MinDensity > 100 {
+		bad("density %g is not a percentage between 0 and 100", opts.MinDensity)
+	}
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{{"required intersections", opts.ReqIntersections}, {"maximum empty cells", opts.MaxEmpty}, {"minimum crossings", opts.MinCrossings}, {"memo size", opts.MemoMB}} {
+		if limit.value < 0 {
+			bad("%s %d is negative", limit.name, limit.value)
+		}
+	}
+
+	letters := 0
+	distinct := make(map[string]bool)
+	for _, w := range words {
+		placed := gridForm(w, opts.folds())
+		distinct[placed] = true
+		n := len([]rune(placed))
+		letters += n
+		if n > opts.GridSize && opts.GridSize > 0 {
+			bad("%s has %d letters but the grid is %dx%d", w, n, opts.GridSize, opts.GridSize)
+		}
+	}
+	// every intersection uses one letter of each of two words
+	if opts.ReqIntersections > letters/2 {
+		bad("%d intersections required but the words have only %d letters, enough for %d", opts.ReqIntersections, letters, letters/2)
+	}
+	// a word crosses each other word at most once
+	if opts.MinCrossings > 0 && len(distinct) <= opts.MinCrossings {
+		bad("every word must cross %d others but there are only %d distinct words", opts.MinCrossings, len(distinct))
+	}
+	// a cell holds at most one letter, so the words bound the fill
+	if need := opts.MinDensity / 100 * float64(opts.GridSize*opts.GridSize); opts.MinDensity <= 100 && letters < int(need+0.5) {
+		bad("density %g%% needs %.0f filled cells but the words have only %d letters", opts.MinDensity, need, letters)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,353 @@
+// file: pdf.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A minimal PDF writer: enough for puzzle pages made of text in the standard
+// fonts, lines and rectangles, without pulling in a dependency.
+// Coordinates are PDF points from the bottom-left corner of the page.
+
+// US Letter, in points.
+const (
+	PDF_PAGE_WIDTH  = 612
+	PDF_PAGE_HEIGHT = 792
+)
+
+// The fonts every page can use, by resource name: the regular and bold faces
+// of the document's font family, and the Asian font text holding CJK
+// characters is drawn in.
+const (
+	PDF_FONT       = "F1"
+	PDF_FONT_BOLD  = "F2"
+	PDF_FONT_CJK   = "F3"
+	PDF_CHAR_WIDTH = 0.55 // average glyph width per point of size, for wrapping
+)
+
+// pdfFontFamilies maps the font families to their standard regular and bold
+// fonts, which every PDF reader has.
+var pdfFontFamilies = map[string][2]string{
+	"Helvetica": {"Helvetica", "Helvetica-Bold"},
+	"Times":     {"Times-Roman", "Times-Bold"},
+	"Courier":   {"Courier", "Courier-Bold"},
+}
+
+// pdfCJKFonts maps the Asian fonts of Adobe's font packs, which PDF readers
+// supply without the font being embedded, to their character collection
+// and the CMap reading UCS-2 text in it.
+var pdfCJKFonts = map[string]struct{ ordering, cmap string }{
+	"STSong-Light":       {"GB1", "UniGB-UCS2-H"},     // simplified Chinese
+	"MSung-Light":        {"CNS1", "UniCNS-UCS2-H"},   // traditional Chinese
+	"KozMinPro-Regular":  {"Japan1", "UniJIS-UCS2-H"}, // Japanese
+	"HYSMyeongJo-Medium": {"Korea1", "UniKS-UCS2-H"},  // Korean
+}
+
+// PDF_CJK_FONT is the Asian font used when the theme names none.
+const PDF_CJK_FONT = "STSong-Light"
+
+// pdfDoc collects pages and writes them out as one file.
+type pdfDoc struct {
+	pages   []*pdfPage
+	font    string // font family; Helvetica if empty
+	cjkFont string // one of pdfCJKFonts; PDF_CJK_FONT if empty
+	ink     string // colour of text and lines; black if empty
+}
+
+// pdfPage is the content stream of one page.
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+// addPage starts a new page and returns it.
+func (d *pdfDoc) addPage() *pdfPage {
+	page := &pdfPage{}
+	page.setStroke(0.5)
+	if d.ink != "" {
+		page.setColour(d.ink)
+	}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// setStroke sets the width of the lines drawn from now on.
+func (p *pdfPage) setStroke(width float64) {
+	fmt.Fprintf(&p.content, "%.2f w\n", width)
+}
+
+// setColour sets the colour of the text and lines drawn from now on.
+func (p *pdfPage) setColour(colour string) {
+	rgb := pdfColour(colour)
+	fmt.Fprintf(&p.content, "%s rg %s RG\n", rgb, rgb)
+}
+
+// save and restore bracket drawing whose line width and colour changes
+// should not outlast it.
+func (p *pdfPage) save()    { p.content.WriteString("q\n") }
+func (p *pdfPage) restore() { p.content.WriteString("Q\n") }
+
+// text draws s with its baseline starting at (x, y). Text holding CJK
+// characters is drawn in PDF_FONT_CJK instead of font.
+func (p *pdfPage) text(x, y, size float64, font, s string) {
+	if hasWide(s) {
+		fmt.Fprintf(&p.content, "BT /%s %.1f Tf %.2f %.2f Td <%s> Tj ET\n", PDF_FONT_CJK, size, x, y, pdfHex(s))
+		return
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+// centredText draws s centred on x.
+func (p *pdfPage) centredText(x, y, size float64, font, s string) {
+	p.text(x-pdfTextWidth(s, size)/2, y, size, font, s)
+}
+
+// rect draws a rectangle with its lower-left corner at (x, y), filled black
+// if fill is set, otherwise outlined.
+func (p *pdfPage) rect(x, y, w, h float64, fill bool) {
+	op := "S"
+	if fill {
+		op = "f"
+	}
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f re %s\n", x, y, w, h, op)
+}
+
+// line draws a line of the given width from (x1, y1) to (x2, y2).
+func (p *pdfPage) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "q %.2f w %.2f %.2f m %.2f %.2f l S Q\n", width, x1, y1, x2, y2)
+}
+
+// fill fills a rectangle in colour.
+func (p *pdfPage) fill(x, y, w, h float64, colour string) {
+	fmt.Fprintf(&p.content, "q %s rg %.2f %.2f %.2f %.2f re f Q\n", pdfColour(colour), x, y, w, h)
+}
+
+// circle outlines a circle of radius r around (x, y), drawn as four Bézier
+// quarter arcs.
+func (p *pdfPage) circle(x, y, r float64) {
+	k := 0.5523 * r // control point distance for a quarter circle
+	fmt.Fprintf(&p.content, "%.2f %.2f m ", x+r, y)
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f %.2f %.2f c ", x+r, y+k, x+k, y+r, x, y+r)
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f %.2f %.2f c ", x-k, y+r, x-r, y+k, x-r, y)
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f %.2f %.2f c ", x-r, y-k, x-k, y-r, x, y-r)
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f %.2f %.2f c S\n", x+k, y-r, x+r, y-k, x+r, y)
+}
+
+// qr draws the QR code q as a square of the given size with its top-left
+// corner at (x, y), including the quiet zone of four modules around it.
+func (p *pdfPage) qr(x, y, size float64, q *qrCode) {
+	module := size / float64(q.size+8)
+	for r := 0; r < q.size; r++ {
+		for c := 0; c < q.size; c++ {
+			if q.modules[r][c] {
+				p.rect(x+float64(c+4)*module, y-float64(r+5)*module, module, module, true)
+			}
+		}
+	}
+}
+
+// write writes the document.
+func (d *pdfDoc) write(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	// 1: catalog, 2: page tree, then a page and its content per page
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+2*i)
+	}
+	family, ok := pdfFontFamilies[d.font]
+	if !ok {
+		family = pdfFontFamilies["Helvetica"]
+	}
+	cjkFont := d.cjkFont
+	if _, ok := pdfCJKFonts[cjkFont]; !ok {
+		cjkFont = PDF_CJK_FONT
+	}
+	// the CIDFont and its descriptor follow the pages
+	cidFont := 3 + 2*len(d.pages)
+	fonts := fmt.Sprintf("<< /%s << /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >> "+
+		"/%s << /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >> "+
+		"/%s << /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /%s /DescendantFonts [%d 0 R] >> >>",
+		PDF_FONT, family[0], PDF_FONT_BOLD, family[1], PDF_FONT_CJK, cjkFont, pdfCJKFonts[cjkFont].cmap, cidFont)
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font %s >> /Contents %d 0 R >>",
+			PDF_PAGE_WIDTH, PDF_PAGE_HEIGHT, fonts, 4+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+	object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (%s) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 >>",
+		cjkFont, pdfCJKFonts[cjkFont].ordering, cidFont+1))
+	object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 6 /FontBBox [0 -200 1000 900] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 700 /StemV 80 >>", cjkFont))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// pdfColour returns a "#rrggbb" colour as PDF colour components, black if
+// it does not parse.
+func pdfColour(colour string) string {
+	rgb, _ := parseColour(colour)
+	return fmt.Sprintf("%.3f %.3f %.3f", rgb[0], rgb[1], rgb[2])
+}
+
+// pdfString escapes s for a PDF string literal in WinAnsiEncoding. Letters
+// outside Latin-1 become '?'.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '’':
+			b.WriteByte('\'')
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "\\%03o", r)
+		}
+	}
+	return b.String()
+}
+
+// pdfHex encodes s as a PDF hex string in UCS-2, for PDF_FONT_CJK.
+// Characters outside the Basic Multilingual Plane become '?'.
+func pdfHex(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xffff {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// pdfTextWidth estimates the width of s in points. CJK characters are
+// square, a full size wide.
+func pdfTextWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		if runeWidth(r) == 2 {
+			width += size
+		} else {
+			width += size * PDF_CHAR_WIDTH
+		}
+	}
+	return width
+}
+
+// pdfWrap splits s into lines at most width points wide. CJK text, which
+// has no spaces, may break between any two characters.
+func pdfWrap(s string, size, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for i, piece := range cjkPieces(word) {
+			next := piece
+			if line != "" && i == 0 {
+				next = line + " " + piece
+			} else if line != "" {
+				next = line + piece
+			}
+			if line != "" && pdfTextWidth(next, size) > width {
+				lines = append(lines, line)
+				next = piece
+			}
+			line = next
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfGrid draws the grid of p in theme with its top-left corner at (x, y)
+// and cells of the given size: blocks, clue numbers, bars, circles and
+// shading, and the letters unless blank is set.
+func pdfGrid(page *pdfPage, p *Puzzle, x, y, cell float64, theme Theme, blank bool) {
+	numbers := make(map[Pos]int)
+	for _, e := range p.entries() {
+		numbers[Pos{e.Row, e.Col}] = e.Number
+	}
+	page.save()
+	defer page.restore()
+	page.setStroke(theme.Stroke)
+	page.setColour(theme.Ink)
+	for r := 0; r < p.Rows; r++ {
+		for c := 0; c < p.Cols; c++ {
+			loc := Pos{r, c}
+			cx, cy := x+float64(c)*cell, y-float64(r+1)*cell
+			ch := p.Cell(r, c)
+			if ch == '#' {
+				pdfBlock(page, cx, cy, cell, theme)
+				continue
+			}
+			switch {
+			case p.Marked(loc, MARK_SHADE):
+				page.fill(cx, cy, cell, cell, theme.Shade)
+			case !strings.EqualFold(theme.Paper, "#FFFFFF"):
+				page.fill(cx, cy, cell, cell, theme.Paper)
+			}
+			page.rect(cx, cy, cell, cell, false)
+			if p.Marked(loc, MARK_CIRCLE) {
+				page.circle(cx+cell/2, cy+cell/2, cell*0.45)
+			}
+			if n, ok := numbers[loc]; ok {
+				size := cell * 0.28
+				nx := cx + 1.5
+				if theme.Numbers == "top-right" || p.rtl {
+					nx = cx + cell - 1.5 - pdfTextWidth(fmt.Sprint(n), size)
+				}
+				page.text(nx, cy+cell-cell*0.3, size, PDF_FONT, fmt.Sprint(n))
+			}
+			if !blank {
+				size := cell * theme.letterSize()
+				page.centredText(cx+cell/2, cy+(cell-size*0.93)/2, size, PDF_FONT, string(ch))
+			}
+			if p.Bar(loc, BAR_RIGHT) {
+				page.line(cx+cell, cy, cx+cell, cy+cell, 6*theme.Stroke)
+			}
+			if p.Bar(loc, BAR_BELOW) {
+				page.line(cx, cy, cx+cell, cy, 6*theme.Stroke)
+			}
+		}
+	}
+}
+
+// pdfBlock draws a block in the style of theme: filled, or outlined and
+// hatched with diagonal lines to save ink.
+func pdfBlock(page *pdfPage, x, y, cell float64, theme Theme) {
+	if theme.BlockStyle != "hatched" {
+		page.fill(x, y, cell, cell, theme.Block)
+		return
+	}
+	page.save()
+	page.setColour(theme.Block)
+	page.rect(x, y, cell, cell, false)
+	for i := 1; i < 4; i++ {
+		d := cell * float64(i) / 4
+		page.line(x, y+d, x+d, y, theme.Stroke)
+		page.line(x+d, y+cell, x+cell, y+d, theme.Stroke)
+	}
+	page.line(x, y+cell, x+cell, y, theme.Stroke)
+	page.restore()
+}
@@ -0,0 +1,240 @@
+// file: post.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// -post sends every puzzle to a chat channel through an incoming webhook,
+// for teams running a weekly puzzle thread. Discord gets the empty grid as
+// an image, the clues, and the answers hidden behind spoiler tags. Slack
+// webhooks take neither images nor spoilers, so Slack gets the empty grid
+// as text and the clues, and the solution is left out.
+
+// POST_CELL is the pixel size of a cell of the posted grid image, and
+// POST_DIGIT_SCALE that of a dot of its clue numbers.
+const (
+	POST_CELL        = 32
+	POST_DIGIT_SCALE = 2
+)
+
+// DISCORD_MAX_CONTENT is the most characters Discord takes in one message;
+// longer clue lists are sent as several.
+const DISCORD_MAX_CONTENT = 2000
+
+// poster sends puzzles to a Slack or Discord webhook.
+type poster struct {
+	webhook string
+	discord bool // Discord rather than Slack
+	client  *http.Client
+}
+
+// newPoster checks an -post webhook URL and tells which service it is.
+func newPoster(webhook string) (*poster, error) {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return nil, err
+	}
+	po := &poster{webhook: webhook, client: &http.Client{Timeout: 30 * time.Second}}
+	switch {
+	case u.Scheme != "https":
+	case u.Host == "hooks.slack.com":
+		return po, nil
+	case (u.Host == "discord.com" || u.Host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/"):
+		po.discord = true
+		return po, nil
+	}
+	return nil, fmt.Errorf("%q is not a Slack or Discord webhook URL", webhook)
+}
+
+// post sends p to the webhook.
+func (po *poster) post(p *Puzzle) error {
+	if po.discord {
+		return po.postDiscord(p)
+	}
+	var text strings.Builder
+	text.WriteString(chatHeader(p, "*"))
+	text.WriteString("```\n")
+	printGrid(&text, p, true, false)
+	text.WriteString("```\n")
+	for _, line := range chatClues(p, "*") {
+		text.WriteString(line + "\n")
+	}
+	body, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		return err
+	}
+	return po.send("application/json", body)
+}
+
+// postDiscord sends p as a message with the grid image, followed by more
+// messages if the clues and answers do not fit in one.
+func (po *poster) postDiscord(p *Puzzle) error {
+	lines := chatClues(p, "**")
+	lines = append(lines, "", "**Solution**")
+	for _, e := range p.entries() {
+		lines = append(lines, fmt.Sprintf("%s: ||%s||", entryLabel(p, e), e.Display))
+	}
+	messages := []string{chatHeader(p, "**")}
+	for _, line := range lines {
+		last := &messages[len(messages)-1]
+		if len(*last)+len(line)+1 > DISCORD_MAX_CONTENT {
+			messages = append(messages, "")
+			last = &messages[len(messages)-1]
+		}
+		*last += line + "\n"
+	}
+
+	img, err := gridPNG(p)
+	if err != nil {
+		return err
+	}
+	for i, content := range messages {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		payload := map[string]any{"content": content}
+		if i == 0 {
+			payload["attachments"] = []map[string]any{{"id": 0, "filename": "grid.png"}}
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if err := form.WriteField("payload_json", string(data)); err != nil {
+			return err
+		}
+		if i == 0 {
+			part, err := form.CreateFormFile("files[0]", "grid.png")
+			if err != nil {
+				return err
+			}
+			if _, err := part.Write(img); err != nil {
+				return err
+			}
+		}
+		if err := form.Close(); err != nil {
+			return err
+		}
+		if err := po.send(form.FormDataContentType(), body.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send posts body to the webhook.
+func (po *poster) send(contentType string, body []byte) error {
+	resp, err := po.client.Post(po.webhook, contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.New("webhook: " + resp.Status + ": " + strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// chatHeader returns the title, author and notes of p as the first lines of
+// a chat message, the title in bold markup.
+func chatHeader(p *Puzzle, bold string) string {
+	m := p.Meta
+	title := m.Title
+	if title == "" {
+		title = "Crossword"
+	}
+	header := bold + title + bold + "\n"
+	if m.Author != "" {
+		header += "by " + m.Author + "\n"
+	}
+	if m.Notes != "" {
+		header += m.Notes + "\n"
+	}
+	return header
+}
+
+// chatClues returns the clue lists of p as chat message lines, the list
+// titles in bold markup. Answers without a clue show their lengths.
+func chatClues(p *Puzzle, bold string) []string {
+	var lines []string
+	for _, list := range []struct {
+		title   string
+		entries []Entry
+	}{{"Across", p.AcrossEntries()}, {"Down", p.DownEntries()}} {
+		lines = append(lines, "", bold+list.title+bold)
+		for _, e := range list.entries {
+			clue := p.ClueText(e) + " (" + e.lengths() + ")"
+			if e.Clue == "" {
+				clue = "(" + e.lengths() + ")"
+			}
+			if dir := otherDirection(p, e); dir != "" {
+				clue += " [" + dir + "]"
+			}
+			lines = append(lines, fmt.Sprintf("%d. %s", e.Number, clue))
+		}
+	}
+	return lines
+}
+
+// gridPNG draws the empty grid of p with its clue numbers as a PNG image.
+func gridPNG(p *Puzzle) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gridImage(p, POST_CELL, POST_DIGIT_SCALE)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gridImage draws the empty grid of p with its clue numbers, cells cell
+// pixels wide and the dots of the numbers scale pixels wide.
+func gridImage(p *Puzzle, cell, scale int) *image.Paletted {
+	numbers := make(map[Pos]int)
+	for _, e := range p.entries() {
+		numbers[Pos{e.Row, e.Col}] = e.Number
+	}
+	img := image.NewPaletted(image.Rect(0, 0, p.Cols*cell+1, p.Rows*cell+1), replayPalette)
+	for r := 0; r < p.Rows; r++ {
+		for c := 0; c < p.Cols; c++ {
+			x, y := c*cell, r*cell
+			fill := uint8(0)
+			if p.Cell(r, c) == '#' {
+				fill = 1
+			}
+			for py := y; py <= y+cell; py++ {
+				for px := x; px <= x+cell; px++ {
+					if px == x || py == y || px == x+cell || py == y+cell {
+						img.SetColorIndex(px, py, 1)
+					} else {
+						img.SetColorIndex(px, py, fill)
+					}
+				}
+			}
+			n := numbers[Pos{r, c}]
+			if n == 0 {
+				continue
+			}
+			digits := fmt.Sprint(n)
+			step := 4 * scale
+			left := x + 1 + scale
+			if p.rtl {
+				left = x + cell - len(digits)*step
+			}
+			for i, d := range digits {
+				drawGlyph(img, d, left+i*step, y+1+scale, scale)
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,17 @@
+// file: progress.go
+package main
+
+// Progress receives updates from generate. OnIteration is called after every
+// shuffle, the one meeting the requirements included, with its number from 0
+// and the best intersection count so far; OnDone is called once when
+// generation returns.
+type Progress interface {
+	OnIteration(i, best int)
+	OnDone()
+}
+
+// noProgress is the default for library use, so nothing is written to stdout.
+type noProgress struct{}
+
+func (noProgress) OnIteration(i, best int) {}
+func (noProgress) OnDone()                 {}
@@ -0,0 +1,519 @@
+// file: puzzle.go
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Entry is one answer of a finished puzzle, with its start cell and clue number.
+type Entry struct {
+	Number    int    // clue number, assigned in reading order
+	Row, Col  int    // start cell (0-based)
+	Direction int    // HORIZONTAL (across) or VERTICAL (down), or another of directionSteps
+	Word      string // letters as placed on the grid
+	Display   string // word as given in the input (e.g. with accents kept)
+	Order     int    // position in the placement sequence, 0 for the first word
+	Clue      string // empty until a ClueProvider supplies one
+}
+
+// isAcross reports whether entries in direction are listed across: those
+// reading along a row either way. Every other direction is listed down.
+func isAcross(direction int) bool {
+	return directionAxes[direction] == directionAxes[HORIZONTAL]
+}
+
+// Metadata is what publishers print around a puzzle. Every field is optional.
+type Metadata struct {
+	Title     string
+	Author    string
+	Copyright string
+	Notes     string // instructions or a preamble shown to solvers
+}
+
+// Puzzle is a finished grid together with its numbered entries.
+type Puzzle struct {
+	Rows, Cols    int
+	Meta          Metadata
+	grid          map[Pos]rune
+	across, down  []Entry
+	intersections int
+	highlights    []Highlight
+	bars          map[Pos]int // BAR_RIGHT | BAR_BELOW per cell; nil for block grids
+	marks         map[Pos]int // MARK_CIRCLE | MARK_SHADE per cell
+	difficulty    *Difficulty // set by Rate
+	rtl           bool        // mirrored for a right-to-left script, see rtl.go
+	undo, redo    []edit      // manual edits, see editor.go
+}
+
+// Highlight marks cells for the exporters that can show them.
+type Highlight struct {
+	Cells  []Pos
+	Colour string // CSS colour; empty for a nina, shown only once the puzzle is solved
+}
+
+// Highlight adds a highlight over cells.
+func (p *Puzzle) Highlight(cells []Pos, colour string) {
+	p.highlights = append(p.highlights, Highlight{Cells: cells, Colour: colour})
+}
+
+// Find returns the entry whose answer is word, ignoring case.
+func (p *Puzzle) Find(word string) (Entry, bool) {
+	for _, e := range p.entries() {
+		if strings.EqualFold(e.Display, word) || strings.EqualFold(e.Word, word) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// newPuzzle builds a Puzzle from the generator's grid and classification,
+// decoding the Loc integers and numbering the entries. display maps placed
+// words back to the input spelling and may be nil.
+func newPuzzle(gridSize int, grid map[Pos]rune, classification map[int][]Placement, display map[string]string) *Puzzle {
+	p := &Puzzle{Rows: gridSize, Cols: gridSize, grid: grid}
+
+	var entries []Entry
+	for dir := range directionSteps {
+		for _, pl := range classification[dir] {
+			e := Entry{Row: pl.Loc / gridSize, Col: pl.Loc % gridSize, Direction: dir, Word: pl.Word, Display: pl.Word, Order: pl.Order}
+			if d, ok := display[pl.Word]; ok {
+				e.Display = d
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	p.setEntries(entries)
+	return p
+}
+
+// setEntries numbers entries in the standard way, every start cell getting
+// the next number in reading order, and stores them as p's across and down.
+func (p *Puzzle) setEntries(entries []Entry) {
+	numbers := make(map[Pos]int)
+	for _, e := range entries {
+		numbers[Pos{e.Row, e.Col}] = 0
+	}
+	starts := make([]Pos, 0, len(numbers))
+	for pos := range numbers {
+		starts = append(starts, pos)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		if starts[i].R != starts[j].R {
+			return starts[i].R < starts[j].R
+		}
+		if p.rtl {
+			return starts[i].C > starts[j].C
+		}
+		return starts[i].C < starts[j].C
+	})
+	for i, pos := range starts {
+		numbers[pos] = i + 1
+	}
+
+	p.across, p.down = nil, nil
+	for _, e := range entries {
+		e.Number = numbers[Pos{e.Row, e.Col}]
+		if isAcross(e.Direction) {
+			p.across = append(p.across, e)
+		} else {
+			p.down = append(p.down, e)
+		}
+	}
+	p.intersections = len(p.crossings())
+	sort.Slice(p.across, func(i, j int) bool { return p.across[i].Number < p.across[j].Number })
+	sort.Slice(p.down, func(i, j int) bool { return p.down[i].Number < p.down[j].Number })
+}
+
+// puzzleFromGrid builds a Puzzle from a finished rows x cols grid, as read
+// from a file: every run of two or more letters becomes an entry. '#' and
+// missing cells are blocks.
+func puzzleFromGrid(rows, cols int, grid map[Pos]rune) *Puzzle {
+	return puzzleFromBarredGrid(rows, cols, grid, nil)
+}
+
+// numberRuns makes every run of p an entry, numbered in reading order.
+func (p *Puzzle) numberRuns() {
+	slots := p.runs()
+	numbers := numberSlots(slots)
+	p.across, p.down = nil, nil
+	for i, s := range slots {
+		var word []rune
+		for _, loc := range s.cells {
+			word = append(word, p.grid[loc])
+		}
+		e := Entry{Number: numbers[i], Row: s.cells[0].R, Col: s.cells[0].C, Direction: s.dir, Word: string(word), Display: string(word), Order: i}
+		if s.dir == HORIZONTAL {
+			p.across = append(p.across, e)
+		} else {
+			p.down = append(p.down, e)
+		}
+	}
+	p.intersections = len(p.crossings())
+	sort.Slice(p.across, func(i, j int) bool { return p.across[i].Number < p.across[j].Number })
+	sort.Slice(p.down, func(i, j int) bool { return p.down[i].Number < p.down[j].Number })
+}
+
+// Keep returns a copy of p holding only the entries for words (matched as
+// by Find), with their positions, numbers and clues unchanged.
+func (p *Puzzle) Keep(words []string) (*Puzzle, error) {
+	out := &Puzzle{Rows: p.Rows, Cols: p.Cols, Meta: p.Meta, grid: make(map[Pos]rune), rtl: p.rtl}
+	for _, w := range words {
+		e, ok := p.Find(w)
+		if !ok {
+			return nil, fmt.Errorf("%s is not in the puzzle", w)
+		}
+		for i, loc := range getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word) {
+			out.grid[loc] = []rune(e.Word)[i]
+		}
+		if isAcross(e.Direction) {
+			out.across = append(out.across, e)
+		} else {
+			out.down = append(out.down, e)
+		}
+	}
+	out.intersections = len(out.crossings())
+	return out, nil
+}
+
+// Cell returns the letter at (r, c), or '#' for blocks and positions outside the grid.
+func (p *Puzzle) Cell(r, c int) rune {
+	if ch, ok := p.grid[Pos{r, c}]; ok {
+		return ch
+	}
+	return '#'
+}
+
+// AcrossEntries returns the across entries ordered by clue number.
+func (p *Puzzle) AcrossEntries() []Entry {
+	return p.across
+}
+
+// DownEntries returns the down entries ordered by clue number.
+func (p *Puzzle) DownEntries() []Entry {
+	return p.down
+}
+
+// Intersections returns the number of cells shared by an across and a down entry.
+func (p *Puzzle) Intersections() int {
+	return p.intersections
+}
+
+// Density returns the percentage of grid cells holding a letter.
+func (p *Puzzle) Density() float64 {
+	if p.Rows*p.Cols == 0 {
+		return 0
+	}
+	return 100 * float64(p.filled()) / float64(p.Rows*p.Cols)
+}
+
+// BoundingEmpty returns the number of empty cells inside the smallest
+// rectangle containing every letter.
+func (p *Puzzle) BoundingEmpty() int {
+	minR, minC, maxR, maxC, ok := p.bounds()
+	if !ok {
+		return 0
+	}
+	return (maxR-minR+1)*(maxC-minC+1) - p.filled()
+}
+
+// bounds returns the first and last row and column holding a letter;
+// ok is false for an empty grid.
+func (p *Puzzle) bounds() (minR, minC, maxR, maxC int, ok bool) {
+	minR, minC, maxR, maxC = p.Rows, p.Cols, -1, -1
+	for loc, ch := range p.grid {
+		if ch == '#' {
+			continue
+		}
+		minR, maxR = min(minR, loc.R), max(maxR, loc.R)
+		minC, maxC = min(minC, loc.C), max(maxC, loc.C)
+	}
+	return minR, minC, maxR, maxC, maxR >= 0
+}
+
+// Crop returns a copy of p cut down to the smallest rectangle holding every
+// letter, widened by margin cells on each side where the grid allows.
+// Coordinates are rebased to the new top-left corner; since entries keep
+// their reading order, clue numbers are unchanged.
+func (p *Puzzle) Crop(margin int) *Puzzle {
+	minR, minC, maxR, maxC, ok := p.bounds()
+	if !ok {
+		return p
+	}
+	minR, minC = max(minR-margin, 0), max(minC-margin, 0)
+	maxR, maxC = min(maxR+margin, p.Rows-1), min(maxC+margin, p.Cols-1)
+
+	out := &Puzzle{Rows: maxR - minR + 1, Cols: maxC - minC + 1, Meta: p.Meta, grid: make(map[Pos]rune), intersections: p.intersections, difficulty: p.difficulty, rtl: p.rtl}
+	for loc, ch := range p.grid {
+		if ch != '#' {
+			out.grid[Pos{loc.R - minR, loc.C - minC}] = ch
+		}
+	}
+	shift := func(entries []Entry) []Entry {
+		moved := make([]Entry, len(entries))
+		for i, e := range entries {
+			e.Row, e.Col = e.Row-minR, e.Col-minC
+			moved[i] = e
+		}
+		return moved
+	}
+	out.across, out.down = shift(p.across), shift(p.down)
+	for _, hl := range p.highlights {
+		cells := make([]Pos, len(hl.Cells))
+		for i, loc := range hl.Cells {
+			cells[i] = Pos{loc.R - minR, loc.C - minC}
+		}
+		out.Highlight(cells, hl.Colour)
+	}
+	for loc, sides := range p.bars {
+		if loc.R >= minR && loc.R <= maxR && loc.C >= minC && loc.C <= maxC {
+			out.SetBar(Pos{loc.R - minR, loc.C - minC}, sides)
+		}
+	}
+	for loc, kind := range p.marks {
+		out.Mark([]Pos{{loc.R - minR, loc.C - minC}}, kind)
+	}
+	return out
+}
+
+// filled counts the cells holding a letter.
+func (p *Puzzle) filled() int {
+	n := 0
+	for _, ch := range p.grid {
+		if ch != '#' {
+			n++
+		}
+	}
+	return n
+}
+
+// Words returns every placed word, across entries first.
+func (p *Puzzle) Words() []string {
+	words := make([]string, 0, len(p.across)+len(p.down))
+	for _, e := range p.entries() {
+		words = append(words, e.Word)
+	}
+	return words
+}
+
+// entries returns a fresh slice of all entries, across first.
+func (p *Puzzle) entries() []Entry {
+	all := make([]Entry, 0, len(p.across)+len(p.down))
+	all = append(all, p.across...)
+	return append(all, p.down...)
+}
+
+// LastPlaced returns the entry the generator placed last.
+func (p *Puzzle) LastPlaced() (Entry, bool) {
+	var last Entry
+	found := false
+	for _, e := range p.entries() {
+		if !found || e.Order > last.Order {
+			last, found = e, true
+		}
+	}
+	return last, found
+}
+
+// crossings reports which cells are shared by an across and a down entry.
+func (p *Puzzle) crossings() map[Pos]bool {
+	covered := make(map[Pos]int)
+	for _, e := range p.entries() {
+		for _, loc := range getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word) {
+			covered[loc]++
+		}
+	}
+	shared := make(map[Pos]bool)
+	for loc, n := range covered {
+		if n > 1 {
+			shared[loc] = true
+		}
+	}
+	return shared
+}
+
+// Crossings returns how many other entries e crosses.
+func (p *Puzzle) Crossings(e Entry) int {
+	shared := p.crossings()
+	n := 0
+	for _, loc := range getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word) {
+		if shared[loc] {
+			n++
+		}
+	}
+	return n
+}
+
+// String returns the solution grid, one row per line.
+func (p *Puzzle) String() string {
+	var b strings.Builder
+	for r := 0; r < p.Rows; r++ {
+		for c := 0; c < p.Cols; c++ {
+			b.WriteRune(p.Cell(r, c))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// CanonicalHash identifies p's layout up to rotation, reflection and
+// transposition: puzzles that are the same grid turned or mirrored, or placed
+// elsewhere on the board, share it. Clues and numbering do not count.
+func (p *Puzzle) CanonicalHash() string {
+	sum := sha256.Sum256([]byte(p.canonicalGrid()))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalGrid returns p's letters cropped to their bounding box, in the
+// first in string order of the eight orientations a rotation, reflection or
+// transposition gives. Layouts that differ only by those share it.
+func (p *Puzzle) canonicalGrid() string {
+	q := p.Crop(0)
+	best := ""
+	for _, transpose := range []bool{false, true} {
+		for _, flipR := range []bool{false, true} {
+			for _, flipC := range []bool{false, true} {
+				rows, cols := q.Rows, q.Cols
+				if transpose {
+					rows, cols = cols, rows
+				}
+				var b strings.Builder
+				for i := 0; i < rows; i++ {
+					for j := 0; j < cols; j++ {
+						r, c := i, j
+						if transpose {
+							r, c = j, i
+						}
+						if flipR {
+							r = q.Rows - 1 - r
+						}
+						if flipC {
+							c = q.Cols - 1 - c
+						}
+						b.WriteRune(q.Cell(r, c))
+					}
+					b.WriteByte('\n')
+				}
+				if s := b.String(); best == "" || s < best {
+					best = s
+				}
+			}
+		}
+	}
+	return best
+}
+
+// puzzleJSON is the serialized form of a Puzzle. Grid rows use '#' for blocks.
+type puzzleJSON struct {
+	Title         string      `json:"title,omitempty"`
+	Author        string      `json:"author,omitempty"`
+	Copyright     string      `json:"copyright,omitempty"`
+	Notes         string      `json:"notes,omitempty"`
+	Rows          int         `json:"rows"`
+	Cols          int         `json:"cols"`
+	Grid          []string    `json:"grid"`
+	Intersections int         `json:"intersections"`
+	Difficulty    *Difficulty `json:"difficulty,omitempty"`
+	Bars          []string    `json:"bars,omitempty"` // see barRows
+	Circled       [][2]int    `json:"circled,omitempty"`
+	Shaded        [][2]int    `json:"shaded,omitempty"`
+	RightToLeft   bool        `json:"rtl,omitempty"` // across entries run leftwards
+	Across        []entryJSON `json:"across"`
+	Down          []entryJSON `json:"down"`
+}
+
+type entryJSON struct {
+	Number  int    `json:"number"`
+	Row     int    `json:"row"`
+	Col     int    `json:"col"`
+	Word    string `json:"word"`
+	Display string `json:"display"`
+	Clue    string `json:"clue,omitempty"`
+	// "5,5,7" for phrases
+	Enumeration string `json:"enumeration,omitempty"`
+	// only for entries not reading across or down
+	Direction string `json:"direction,omitempty"`
+}
+
+func (p *Puzzle) MarshalJSON() ([]byte, error) {
+	out := puzzleJSON{
+		Title:         p.Meta.Title,
+		Author:        p.Meta.Author,
+		Copyright:     p.Meta.Copyright,
+		Notes:         p.Meta.Notes,
+		Rows:          p.Rows,
+		Cols:          p.Cols,
+		Grid:          strings.Split(strings.TrimSuffix(p.String(), "\n"), "\n"),
+		Intersections: p.intersections,
+		Difficulty:    p.difficulty,
+		Across:        []entryJSON{},
+		Down:          []entryJSON{},
+		RightToLeft:   p.rtl,
+	}
+	if p.Barred() {
+		out.Bars = p.barRows()
+	}
+	for _, loc := range p.markedCells(MARK_CIRCLE) {
+		out.Circled = append(out.Circled, [2]int{loc.R, loc.C})
+	}
+	for _, loc := range p.markedCells(MARK_SHADE) {
+		out.Shaded = append(out.Shaded, [2]int{loc.R, loc.C})
+	}
+	for _, e := range p.across {
+		out.Across = append(out.Across, entryJSON{e.Number, e.Row, e.Col, e.Word, e.Display, p.resolveClue(e.Clue), e.Enumeration(), otherDirection(p, e)})
+	}
+	for _, e := range p.down {
+		out.Down = append(out.Down, entryJSON{e.Number, e.Row, e.Col, e.Word, e.Display, p.resolveClue(e.Clue), e.Enumeration(), otherDirection(p, e)})
+	}
+	return json.Marshal(out)
+}
+
+// writeNDJSON writes p as a single line of JSON. Written to stdout, a batch
+// is newline-delimited JSON, a line per puzzle as soon as it is generated.
+func writeNDJSON(w io.Writer, p *Puzzle) error {
+	return writeJSONLine(w, p)
+}
+
+// writeJSONLine writes v as one line of JSON.
+func writeJSONLine(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// otherDirection names e's direction unless it is plain across or down of
+// p, across running leftwards in a right-to-left puzzle.
+func otherDirection(p *Puzzle, e Entry) string {
+	across := HORIZONTAL
+	if p.rtl {
+		across = LEFTWARD
+	}
+	if e.Direction == across || e.Direction == VERTICAL {
+		return ""
+	}
+	return directionNames[e.Direction]
+}
+
+// checkAcrossDown fails if p has entries other than across and down, which
+// format cannot express, or reads right to left, which none of the
+// interchange formats record.
+func checkAcrossDown(p *Puzzle, format string) error {
+	if p.rtl {
+		return fmt.Errorf("%s: right-to-left puzzles cannot be written", format)
+	}
+	for _, e := range p.entries() {
+		if dir := otherDirection(p, e); dir != "" {
+			return fmt.Errorf("%s: %s runs %s; only across and down entries can be written", format, e.Word, dir)
+		}
+	}
+	return nil
+}
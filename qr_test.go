@@ -0,0 +1,126 @@
+// file: qr_test.go
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeQRVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes int
+		size  int // 0 for too long
+	}{
+		{"empty", 0, 21},
+		{"fills version 1", 14, 21},
+		{"one byte over version 1", 15, 25},
+		{"fills version 9", 180, 53},
+		{"needs a 16-bit count", 181, 57},
+		{"fills version 10", 213, 57},
+		{"too long", 214, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := encodeQR(strings.Repeat("a", tt.bytes))
+			if tt.size == 0 {
+				if err == nil {
+					t.Fatalf("encoded %d bytes as a %dx%d symbol", tt.bytes, q.size, q.size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.size != tt.size || len(q.modules) != tt.size {
+				t.Errorf("size %d, want %d", q.size, tt.size)
+			}
+		})
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// HELLO WORLD as 1-M, the example worked through in most QR guides
+	tests := []struct {
+		name     string
+		data, ec []byte
+	}{
+		{"HELLO WORLD 1-M",
+			[]byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17},
+			[]byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}},
+		{"all zero", make([]byte, 16), make([]byte, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rsRemainder(tt.data, rsDivisor(len(tt.ec))); !bytes.Equal(got, tt.ec) {
+				t.Errorf("rsRemainder = %v, want %v", got, tt.ec)
+			}
+		})
+	}
+}
+
+func TestQRFormatBits(t *testing.T) {
+	// level M format information for each mask, from the QR specification
+	want := [8]int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+	for mask, bits := range want {
+		q := newQR(1)
+		q.drawFormat(mask)
+		first, second := 0, 0
+		for i := 0; i < 15; i++ {
+			var a, b bool
+			switch {
+			case i <= 5:
+				a = q.modules[i][8]
+			case i <= 7:
+				a = q.modules[i+1][8]
+			case i == 8:
+				a = q.modules[8][7]
+			default:
+				a = q.modules[8][14-i]
+			}
+			if i < 8 {
+				b = q.modules[8][q.size-1-i]
+			} else {
+				b = q.modules[q.size-15+i][8]
+			}
+			if a {
+				first |= 1 << i
+			}
+			if b {
+				second |= 1 << i
+			}
+		}
+		if first != bits || second != bits {
+			t.Errorf("mask %d: format bits %#x and %#x, want %#x", mask, first, second, bits)
+		}
+	}
+}
+
+func TestQRVersionBits(t *testing.T) {
+	// version information from the QR specification
+	tests := []struct {
+		version, bits int
+	}{
+		{7, 0x07C94},
+		{8, 0x085BC},
+		{9, 0x09A99},
+		{10, 0x0A4D3},
+	}
+	for _, tt := range tests {
+		q := newQR(tt.version)
+		below, right := 0, 0
+		for i := 0; i < 18; i++ {
+			a, b := q.size-11+i%3, i/3
+			if q.modules[b][a] {
+				right |= 1 << i
+			}
+			if q.modules[a][b] {
+				below |= 1 << i
+			}
+		}
+		if right != tt.bits || below != tt.bits {
+			t.Errorf("version %d: bits %#x and %#x, want %#x", tt.version, right, below, tt.bits)
+		}
+	}
+}
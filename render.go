@@ -0,0 +1,409 @@
+// file: render.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ANSI escape sequences used by the coloured text output.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiCross  = "\x1b[1;33m" // bold yellow: cells shared by two entries
+	ansiRecent = "\x1b[36m"   // cyan: the word placed last
+	ansiCircle = "\x1b[4m"    // underline: circled cells
+	ansiShade  = "\x1b[7m"    // reverse video: shaded cells
+)
+
+// gridRenderer draws the grid of p; blank hides the letters, color allows ANSI colors.
+type gridRenderer func(w io.Writer, p *Puzzle, blank, color bool)
+
+// gridRenderers maps the -format names to their grid renderers.
+var gridRenderers = map[string]gridRenderer{
+	"text":  printGrid,
+	"box":   printBoxGrid,
+	"emoji": printEmojiGrid,
+	"arrow": printArrowGrid,
+}
+
+// gridFormats returns the known -format names, sorted.
+func gridFormats() []string {
+	names := make([]string, 0, len(gridRenderers))
+	for name := range gridRenderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exporter writes a puzzle in a file format other programs read.
+type exporter struct {
+	ext   string // file extension for -out
+	write func(w io.Writer, p *Puzzle) error
+}
+
+// exporters maps the -export names to their writers.
+var exporters = map[string]exporter{
+	"accessible": {".txt", writeAccessible},
+	"brf":        {".brf", writeBRF},
+	"ccxml":      {".xml", writeCCXML},
+	"escpos":     {".bin", writeESCPOS},
+	"exolve":     {".exolve", writeExolve},
+	"ipuz":       {".ipuz", writeIpuz},
+	"markdown":   {".md", writeMarkdown},
+	"ndjson":     {".json", writeNDJSON},
+	"xd":         {".xd", writeXD},
+}
+
+// importers maps file extensions to the readers for -import.
+var importers = map[string]func(r io.Reader) (*Puzzle, error){
+	".ipuz": readIpuz,
+	".xd":   readXD,
+}
+
+// readPuzzleFile loads a finished puzzle, picking the reader by extension.
+func readPuzzleFile(path string) (*Puzzle, error) {
+	read, ok := importers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown puzzle file type", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// exportFormats returns the known -export names, sorted.
+func exportFormats() []string {
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writePuzzle prints the (optional) empty puzzle, with the word bank under it
+// if wordBank is set, the solution and the entries.
+func writePuzzle(w io.Writer, p *Puzzle, grid gridRenderer, showBlank, wordBank, color bool) {
+	writeMeta(w, p.Meta)
+	if showBlank {
+		fmt.Fprintln(w, "Puzzle:")
+		grid(w, p, true, color)
+		if wordBank {
+			fmt.Fprintf(w, "\nWord bank: %s\n", strings.Join(p.WordBank(), ", "))
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "Crossword:")
+	grid(w, p, false, color)
+	fmt.Fprintf(w, "\nIntersections: %d\n", p.Intersections())
+	if p.difficulty != nil {
+		fmt.Fprintf(w, "Difficulty: %s (%.0f/100)\n", p.difficulty.Level, p.difficulty.Score)
+	}
+
+	fmt.Fprintln(w, "\nAcross:")
+	for _, e := range p.AcrossEntries() {
+		writeEntry(w, p, e)
+	}
+	fmt.Fprintln(w, "Down:")
+	for _, e := range p.DownEntries() {
+		writeEntry(w, p, e)
+	}
+}
+
+// WordBank returns the answers of p in alphabetical order, as printed for
+// fill-in puzzles, so the list gives nothing away about where they go.
+func (p *Puzzle) WordBank() []string {
+	var words []string
+	for _, e := range p.entries() {
+		words = append(words, e.Display)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// writeMeta prints the title, author and notes above a puzzle, if set, and
+// the copyright.
+func writeMeta(w io.Writer, m Metadata) {
+	if m == (Metadata{}) {
+		return
+	}
+	if m.Title != "" {
+		fmt.Fprintln(w, m.Title)
+	}
+	if m.Author != "" {
+		fmt.Fprintln(w, "by "+m.Author)
+	}
+	if m.Copyright != "" {
+		fmt.Fprintln(w, "© "+strings.TrimPrefix(m.Copyright, "© "))
+	}
+	if m.Notes != "" {
+		fmt.Fprintln(w, "\n"+m.Notes)
+	}
+	fmt.Fprintln(w)
+}
+
+// writeEntry prints one line of the entry lists of p, with the clue if there
+// is one.
+func writeEntry(w io.Writer, p *Puzzle, e Entry) {
+	where := fmt.Sprintf("row %d, col %d", e.Row+1, e.Col+1)
+	if dir := otherDirection(p, e); dir != "" {
+		where += ", " + dir
+	}
+	if e.Clue != "" {
+		fmt.Fprintf(w, "  %d. %s: %s (%s)\n", e.Number, p.ClueText(e), e.Display, where)
+		return
+	}
+	fmt.Fprintf(w, "  %d. %s (%s)\n", e.Number, e.Display, where)
+}
+
+// writeKrissKross prints p as a fill-in puzzle: the empty grid and the word
+// bank grouped by length, with the solution if showKey is set.
+func writeKrissKross(w io.Writer, p *Puzzle, grid gridRenderer, showKey, color bool) {
+	writeMeta(w, p.Meta)
+	fmt.Fprintln(w, "Kriss-kross:")
+	grid(w, p, true, color)
+
+	byLen := make(map[int][]string)
+	for _, e := range p.entries() {
+		n := len([]rune(e.Word))
+		byLen[n] = append(byLen[n], e.Display)
+	}
+	lengths := make([]int, 0, len(byLen))
+	for n := range byLen {
+		lengths = append(lengths, n)
+	}
+	sort.Ints(lengths)
+	fmt.Fprintln(w, "\nWords:")
+	for _, n := range lengths {
+		sort.Strings(byLen[n])
+		fmt.Fprintf(w, "  %d letters: %s\n", n, strings.Join(byLen[n], ", "))
+	}
+
+	if showKey {
+		fmt.Fprintln(w, "\nSolution:")
+		grid(w, p, false, color)
+	}
+}
+
+// cellColors returns the color of every highlighted cell of the solution:
+// intersections first, then the most recently placed word.
+func cellColors(p *Puzzle) map[Pos]string {
+	colors := make(map[Pos]string)
+	if last, ok := p.LastPlaced(); ok {
+		for _, loc := range getSequence(Pos{last.Row, last.Col}, last.Direction, last.Word) {
+			colors[loc] = ansiRecent
+		}
+	}
+	for loc := range p.crossings() {
+		colors[loc] = ansiCross
+	}
+	return colors
+}
+
+// markEscapes returns the ANSI attributes for the marks of loc.
+func markEscapes(p *Puzzle, loc Pos) string {
+	escapes := ""
+	if p.Marked(loc, MARK_CIRCLE) {
+		escapes += ansiCircle
+	}
+	if p.Marked(loc, MARK_SHADE) {
+		escapes += ansiShade
+	}
+	return escapes
+}
+
+// markSides returns what the box grid draws either side of the letter of
+// loc: parentheses for a circle, shading for a shaded cell.
+func markSides(p *Puzzle, loc Pos) (string, string) {
+	switch {
+	case p.Marked(loc, MARK_CIRCLE):
+		return "(", ")"
+	case p.Marked(loc, MARK_SHADE):
+		return "░", "░"
+	}
+	return " ", " "
+}
+
+// --- printGrid
+// With blank set, letter cells are printed as empty squares ('_') so the output
+// can be handed to a solver; unused cells are blocks ('#') either way.
+// With color set, blocks are dimmed and the solution highlights intersections
+// and the most recently placed word, and circled and shaded cells are
+// underlined and reversed. Bars are drawn as '|' between cells and as a line
+// of '-' under the row they close. In a grid holding CJK characters every
+// cell is two columns wide.
+func printGrid(w io.Writer, p *Puzzle, blank, color bool) {
+	var colors map[Pos]string
+	if color && !blank {
+		colors = cellColors(p)
+	}
+	width := 1
+	if p.wide() {
+		width = 2
+	}
+
+	for r := 0; r < p.Rows; r++ {
+		var line strings.Builder
+		under := make([]string, p.Cols)
+		barsBelow := false
+		for c := 0; c < p.Cols; c++ {
+			if c > 0 {
+				if p.Bar(Pos{r, c - 1}, BAR_RIGHT) {
+					line.WriteByte('|')
+				} else {
+					line.WriteByte(' ')
+				}
+			}
+			under[c] = strings.Repeat(" ", width)
+			if p.Bar(Pos{r, c}, BAR_BELOW) {
+				under[c], barsBelow = strings.Repeat("-", width), true
+			}
+			ch := p.Cell(r, c)
+			if blank && ch != '#' {
+				ch = '_'
+			}
+			cell := string(ch)
+			if color && ch == '#' {
+				cell = ansiDim + cell + ansiReset
+			} else if col, ok := colors[Pos{r, c}]; ok {
+				cell = col + cell + ansiReset
+			}
+			if color && p.marks[Pos{r, c}] != 0 {
+				cell = markEscapes(p, Pos{r, c}) + cell + ansiReset
+			}
+			line.WriteString(padCell(cell, ch, width))
+		}
+		fmt.Fprintln(w, line.String())
+		if barsBelow && r < p.Rows-1 {
+			fmt.Fprintln(w, strings.TrimRight(strings.Join(under, " "), " "))
+		}
+	}
+}
+
+// --- printBoxGrid
+// Draws every cell as a box-drawing square three columns wide and two lines
+// high: the clue number in the top-left corner, the letter centred below it.
+// Blocks are filled in and bars drawn as heavy lines; circled letters are
+// put in parentheses and shaded ones between light shade. Unlike printGrid,
+// columns stay aligned wherever the output is pasted, as long as the font is
+// monospaced. Cells are a column wider in a grid holding CJK characters.
+func printBoxGrid(w io.Writer, p *Puzzle, blank, color bool) {
+	width := 3
+	if p.wide() {
+		width = 4
+	}
+	var colors map[Pos]string
+	if color && !blank {
+		colors = cellColors(p)
+	}
+	numbers := make(map[Pos]int)
+	for _, e := range p.entries() {
+		numbers[Pos{e.Row, e.Col}] = e.Number
+	}
+
+	// border returns the horizontal rule above row r (r == p.Rows: the bottom one)
+	border := func(r int) string {
+		left, mid, right := "├", "┼", "┤"
+		switch r {
+		case 0:
+			left, mid, right = "┌", "┬", "┐"
+		case p.Rows:
+			left, mid, right = "└", "┴", "┘"
+		}
+		line := left
+		for c := 0; c < p.Cols; c++ {
+			if c > 0 {
+				line += mid
+			}
+			if r > 0 && r < p.Rows && p.Bar(Pos{r - 1, c}, BAR_BELOW) {
+				line += strings.Repeat("━", width)
+			} else {
+				line += strings.Repeat("─", width)
+			}
+		}
+		return line + right
+	}
+
+	for r := 0; r < p.Rows; r++ {
+		fmt.Fprintln(w, border(r))
+		top, bottom := "│", "│"
+		for c := 0; c < p.Cols; c++ {
+			sep := "│"
+			if c < p.Cols-1 && p.Bar(Pos{r, c}, BAR_RIGHT) {
+				sep = "┃"
+			}
+			ch := p.Cell(r, c)
+			if ch == '#' {
+				fill := strings.Repeat("█", width)
+				if color {
+					fill = ansiDim + fill + ansiReset
+				}
+				top += fill + sep
+				bottom += fill + sep
+				continue
+			}
+
+			num := strings.Repeat(" ", width)
+			if n, ok := numbers[Pos{r, c}]; ok {
+				num = fmt.Sprintf("%-*d", width, n)
+			}
+			open, shut := markSides(p, Pos{r, c})
+			letter := open + padCell(string(ch), ch, width-2) + shut
+			if blank {
+				letter = open + strings.Repeat(" ", width-2) + shut
+			} else if col, ok := colors[Pos{r, c}]; ok {
+				letter = col + letter + ansiReset
+			}
+			top += num + sep
+			bottom += letter + sep
+		}
+		fmt.Fprintln(w, top)
+		fmt.Fprintln(w, bottom)
+	}
+	fmt.Fprintln(w, border(p.Rows))
+}
+
+// --- printEmojiGrid
+// Draws the grid in emoji for pasting into chat apps, where monospaced text
+// does not survive: ⬛ for blocks, ⬜ for empty cells (🟡 circled, 🟨
+// shaded), and the solution in regional-indicator letters. Those are kept
+// apart by a zero-width space so that pairs are not shown as flags. Letters
+// outside A-Z are printed as they are. Bars and colours are not shown.
+func printEmojiGrid(w io.Writer, p *Puzzle, blank, color bool) {
+	for r := 0; r < p.Rows; r++ {
+		var line strings.Builder
+		for c := 0; c < p.Cols; c++ {
+			ch := p.Cell(r, c)
+			switch {
+			case ch == '#':
+				line.WriteString("⬛")
+			case !blank && ch >= 'A' && ch <= 'Z':
+				line.WriteRune(0x1F1E6 + ch - 'A')
+				line.WriteRune('\u200b') // zero-width space
+			case !blank:
+				// emoji are two columns wide, as are CJK characters
+				line.WriteString(padCell(string(ch), ch, 2))
+			case p.Marked(Pos{r, c}, MARK_CIRCLE):
+				line.WriteString("🟡")
+			case p.Marked(Pos{r, c}, MARK_SHADE):
+				line.WriteString("🟨")
+			default:
+				line.WriteString("⬜")
+			}
+		}
+		fmt.Fprintln(w, line.String())
+	}
+}
@@ -0,0 +1,198 @@
+// file: replay.go
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"strings"
+)
+
+// A replay is the construction of a puzzle step by step: every word the
+// search put on the grid and took off again, in order, during the shuffle
+// that produced the puzzle. Replaying it shows the backtracking at work. It
+// is written as a JSON event log, which the browser page can play back, or
+// as an animated GIF.
+//
+// Mini and giant grids are not built by the backtracking search and have no
+// replay. Coordinates are those of the generated grid, before any -crop.
+
+// What a replay event does to the grid.
+const (
+	REPLAY_ADD    = "add"
+	REPLAY_REMOVE = "remove"
+)
+
+// GIF replay layout: pixels per cell, the most frames written (longer
+// replays skip events evenly), and frame delays in hundredths of a second.
+const (
+	REPLAY_CELL        = 20
+	REPLAY_MAX_FRAMES  = 400
+	REPLAY_FRAME_DELAY = 12
+	REPLAY_FINAL_DELAY = 400
+)
+
+// ReplayEvent is one word put on or taken off the grid.
+type ReplayEvent struct {
+	Op        string `json:"op"` // REPLAY_ADD or REPLAY_REMOVE
+	Word      string `json:"word"`
+	Row       int    `json:"row"` // of the first letter, 0-based
+	Col       int    `json:"col"`
+	Direction string `json:"direction"` // as for -directions
+}
+
+// Replay is the construction of the best layout of a generation run. Set
+// Options.Replay to collect it; each generation replaces its contents.
+type Replay struct {
+	Size   int           `json:"size"` // rows and columns of the grid
+	Events []ReplayEvent `json:"events"`
+}
+
+// record appends an event placing or removing word at sequence. A nil log
+// records nothing.
+func (r *Replay) record(op, word string, sequence []Pos, direction int) {
+	if r == nil {
+		return
+	}
+	r.Events = append(r.Events, ReplayEvent{Op: op, Word: word, Row: sequence[0].R, Col: sequence[0].C, Direction: directionNames[direction]})
+}
+
+// writeJSON writes the replay as its JSON event log.
+func (r *Replay) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// replayPalette: paper, ink, empty cell, the word just added, the cells of
+// the word just removed.
+var replayPalette = color.Palette{
+	color.White,
+	color.Black,
+	color.RGBA{0xdd, 0xdd, 0xdd, 0xff},
+	color.RGBA{0x9c, 0xe0, 0x9c, 0xff},
+	color.RGBA{0xf0, 0x9a, 0x9a, 0xff},
+}
+
+// writeGIF writes the replay as an animated GIF, a frame per event with the
+// changed word highlighted, holding the finished grid at the end.
+func (r *Replay) writeGIF(w io.Writer) error {
+	letters := make(map[Pos]rune)
+	uses := make(map[Pos]int) // words holding each cell
+	stride := (len(r.Events) + REPLAY_MAX_FRAMES - 1) / REPLAY_MAX_FRAMES
+	anim := &gif.GIF{}
+	for i, e := range r.Events {
+		dirs, err := parseDirections(e.Direction)
+		if err != nil {
+			return err
+		}
+		sequence := getSequence(Pos{e.Row, e.Col}, dirs[0], e.Word)
+		for j, letter := range []rune(e.Word) {
+			loc := sequence[j]
+			if e.Op == REPLAY_ADD {
+				letters[loc] = letter
+				uses[loc]++
+			} else if uses[loc]--; uses[loc] == 0 {
+				delete(letters, loc)
+			}
+		}
+		last := i == len(r.Events)-1
+		if i%stride != 0 && !last {
+			continue
+		}
+		highlight := uint8(3)
+		if e.Op == REPLAY_REMOVE {
+			highlight = 4
+		}
+		delay := REPLAY_FRAME_DELAY
+		if last {
+			delay = REPLAY_FINAL_DELAY
+		}
+		anim.Image = append(anim.Image, r.frame(letters, sequence, highlight))
+		anim.Delay = append(anim.Delay, delay)
+	}
+	if len(anim.Image) == 0 {
+		anim.Image, anim.Delay = []*image.Paletted{r.frame(letters, nil, 0)}, []int{REPLAY_FINAL_DELAY}
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// frame draws the grid holding letters, with the cells of changed in the
+// palette colour highlight.
+func (r *Replay) frame(letters map[Pos]rune, changed []Pos, highlight uint8) *image.Paletted {
+	side := r.Size*REPLAY_CELL + 1
+	img := image.NewPaletted(image.Rect(0, 0, side, side), replayPalette)
+	marked := make(map[Pos]bool)
+	for _, loc := range changed {
+		marked[loc] = true
+	}
+	for row := 0; row < r.Size; row++ {
+		for col := 0; col < r.Size; col++ {
+			loc := Pos{row, col}
+			x, y := col*REPLAY_CELL, row*REPLAY_CELL
+			fill := uint8(2)
+			if _, ok := letters[loc]; ok {
+				fill = 0
+			}
+			if marked[loc] {
+				fill = highlight
+			}
+			for py := y; py <= y+REPLAY_CELL; py++ {
+				for px := x; px <= x+REPLAY_CELL; px++ {
+					if px == x || py == y || px == x+REPLAY_CELL || py == y+REPLAY_CELL {
+						img.SetColorIndex(px, py, 1)
+					} else {
+						img.SetColorIndex(px, py, fill)
+					}
+				}
+			}
+			if letter, ok := letters[loc]; ok {
+				drawGlyph(img, letter, x+(REPLAY_CELL-3*GLYPH_SCALE)/2+1, y+(REPLAY_CELL-5*GLYPH_SCALE)/2+1, GLYPH_SCALE)
+			}
+		}
+	}
+	return img
+}
+
+// GLYPH_SCALE is the pixel size of a dot of the replay font's letters.
+const GLYPH_SCALE = 3
+
+// glyphs is a 3x5 dot font for the replay and the posted grid image, a row
+// of dots per word; characters outside it are drawn as a solid block.
+var glyphs = map[rune]string{
+	'A': ".#. #.# ### #.# #.#", 'B': "##. #.# ##. #.# ##.", 'C': ".## #.. #.. #.. .##",
+	'D': "##. #.# #.# #.# ##.", 'E': "### #.. ##. #.. ###", 'F': "### #.. ##. #.. #..",
+	'G': ".## #.. #.# #.# .##", 'H': "#.# #.# ### #.# #.#", 'I': "### .#. .#. .#. ###",
+	'J': "..# ..# ..# #.# .#.", 'K': "#.# #.# ##. #.# #.#", 'L': "#.. #.. #.. #.. ###",
+	'M': "#.# ### ### #.# #.#", 'N': "##. #.# #.# #.# #.#", 'O': ".#. #.# #.# #.# .#.",
+	'P': "##. #.# ##. #.. #..", 'Q': ".#. #.# #.# ##. .##", 'R': "##. #.# ##. #.# #.#",
+	'S': ".## #.. .#. ..# ##.", 'T': "### .#. .#. .#. .#.", 'U': "#.# #.# #.# #.# ###",
+	'V': "#.# #.# #.# #.# .#.", 'W': "#.# #.# ### ### #.#", 'X': "#.# #.# .#. #.# #.#",
+	'Y': "#.# #.# .#. .#. .#.", 'Z': "### ..# .#. #.. ###",
+	'0': "### #.# #.# #.# ###", '1': ".#. ##. .#. .#. ###", '2': "##. ..# .#. #.. ###",
+	'3': "##. ..# .#. ..# ##.", '4': "#.# #.# ### ..# ..#", '5': "### #.. ##. ..# ##.",
+	'6': ".## #.. ### #.# ###", '7': "### ..# .#. .#. .#.", '8': "### #.# ### #.# ###",
+	'9': "### #.# ### ..# ##.",
+}
+
+// drawGlyph draws letter in ink with its top left corner at x, y, each dot
+// scale pixels wide.
+func drawGlyph(img *image.Paletted, letter rune, x, y, scale int) {
+	rows := []string{"###", "###", "###", "###", "###"}
+	if dots, ok := glyphs[letter]; ok {
+		rows = strings.Fields(dots)
+	}
+	for i := 0; i < 15; i++ {
+		if rows[i/3][i%3] != '#' {
+			continue
+		}
+		dx, dy := i%3*scale, i/3*scale
+		for py := 0; py < scale; py++ {
+			for px := 0; px < scale; px++ {
+				img.SetColorIndex(x+dx+px, y+dy+py, 1)
+			}
+		}
+	}
+}
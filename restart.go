@@ -0,0 +1,74 @@
+// file: restart.go
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Every shuffle of the search gets a depth budget: the placements it may try
+// before it is abandoned for the next shuffle. With the fixed schedule each
+// shuffle may use all of MaxDepth, so a shuffle that leads nowhere costs as
+// much as one that succeeds. The growing schedules start small and give
+// longer runs only now and then, so unlucky shuffles are cut short while
+// hard word sets still get deep searches. MaxDepth caps every budget.
+
+// restartSchedule returns the budget of the attempt-th shuffle (0-based),
+// given the unit budget and the cap.
+type restartSchedule func(attempt, unit, max int) int
+
+var restartSchedules = map[string]restartSchedule{
+	"fixed": func(attempt, unit, max int) int { return max },
+	"luby": func(attempt, unit, max int) int {
+		return min(unit*luby(attempt+1), max)
+	},
+	"geometric": func(attempt, unit, max int) int {
+		budget := unit
+		for i := 0; i < attempt && budget < max; i++ {
+			budget *= 2
+		}
+		return min(budget, max)
+	},
+}
+
+// restartNames lists the schedules for help and error messages.
+func restartNames() []string {
+	names := make([]string, 0, len(restartSchedules))
+	for name := range restartSchedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// luby returns the i-th term (1-based) of the Luby sequence
+// 1 1 2 1 1 2 4 1 1 2 1 1 2 4 8 ...
+func luby(i int) int {
+	for {
+		k := 1
+		for (1<<k)-1 < i {
+			k++
+		}
+		if (1<<k)-1 == i {
+			return 1 << (k - 1)
+		}
+		i -= (1 << (k - 1)) - 1
+	}
+}
+
+// budget returns the depth budget of shuffle iter under opts.Restart.
+func (opts Options) budget(iter int) int {
+	schedule, ok := restartSchedules[opts.Restart]
+	if !ok || opts.RestartUnit <= 0 {
+		return opts.MaxDepth
+	}
+	return schedule(iter, opts.RestartUnit, opts.MaxDepth)
+}
+
+// checkRestart reports an unknown schedule name; "" means fixed.
+func checkRestart(name string) error {
+	if _, ok := restartSchedules[name]; !ok && name != "" {
+		return fmt.Errorf("unknown restart schedule %q", name)
+	}
+	return nil
+}
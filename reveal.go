@@ -0,0 +1,90 @@
+// file: reveal.go
+package main
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// Checking and revealing answers, for anything letting people solve a
+// puzzle interactively. Keeping the rules here means every player agrees on
+// them: a guess is compared cell by cell after upper-casing, blanks are
+// neither right nor wrong, and an entry is identified by its start cell and
+// direction, so take entries from the puzzle being played (a Crop or
+// RightToLeft moves them).
+
+// Results of CheckGuess, per cell.
+const (
+	GUESS_EMPTY = iota // no letter guessed
+	GUESS_RIGHT
+	GUESS_WRONG
+)
+
+// guessBlanks are the characters a guess may use for a cell left empty.
+var guessBlanks = map[rune]bool{' ': true, '?': true, '.': true, '_': true}
+
+// RevealLetter returns the letter of e's answer at index, counted from 0.
+func (p *Puzzle) RevealLetter(e Entry, index int) (rune, error) {
+	cells, err := p.entryCells(e)
+	if err != nil {
+		return 0, err
+	}
+	if index < 0 || index >= len(cells) {
+		return 0, fmt.Errorf("%s has no letter %d", entryLabel(p, e), index+1)
+	}
+	return p.grid[cells[index]], nil
+}
+
+// RevealWord returns e's answer as it stands in the grid.
+func (p *Puzzle) RevealWord(e Entry) (string, error) {
+	cells, err := p.entryCells(e)
+	if err != nil {
+		return "", err
+	}
+	word := make([]rune, len(cells))
+	for i, loc := range cells {
+		word[i] = p.grid[loc]
+	}
+	return string(word), nil
+}
+
+// CheckGuess compares guess with e's answer and returns GUESS_EMPTY,
+// GUESS_RIGHT or GUESS_WRONG for each cell of the entry. Cells the guess
+// leaves blank (see guessBlanks) or does not reach are empty; a guess longer
+// than the entry is an error.
+func (p *Puzzle) CheckGuess(e Entry, guess string) ([]int, error) {
+	cells, err := p.entryCells(e)
+	if err != nil {
+		return nil, err
+	}
+	letters := []rune(guess)
+	if len(letters) > len(cells) {
+		return nil, fmt.Errorf("guess %q is longer than %s (%d letters)", guess, entryLabel(p, e), len(cells))
+	}
+	result := make([]int, len(cells))
+	for i, g := range letters {
+		switch {
+		case guessBlanks[g]:
+			result[i] = GUESS_EMPTY
+		case unicode.ToUpper(g) == p.grid[cells[i]]:
+			result[i] = GUESS_RIGHT
+		default:
+			result[i] = GUESS_WRONG
+		}
+	}
+	return result, nil
+}
+
+// entryCells returns the cells of e, after checking that p has an entry
+// starting at the same cell in the same direction.
+func (p *Puzzle) entryCells(e Entry) ([]Pos, error) {
+	if e.Direction < 0 || e.Direction >= len(directionNames) {
+		return nil, fmt.Errorf("unknown direction %d", e.Direction)
+	}
+	for _, own := range p.entries() {
+		if own.Row == e.Row && own.Col == e.Col && own.Direction == e.Direction {
+			return getSequence(Pos{own.Row, own.Col}, own.Direction, own.Word), nil
+		}
+	}
+	return nil, fmt.Errorf("no entry starts at row %d, col %d going %s", e.Row+1, e.Col+1, directionNames[e.Direction])
+}
@@ -0,0 +1,60 @@
+// file: rtl.go
+package main
+
+// Hebrew and Arabic puzzles read right to left: across answers start in
+// their rightmost cell and run leftwards, and clue numbers follow the
+// reading order from the top-right corner. The generator lays words out
+// left to right as usual and RightToLeft mirrors the finished puzzle, which
+// turns every across answer into a leftward one and leaves the down answers
+// reading down. Renderers draw the mirrored cells where they are, and the
+// PDF puts clue numbers in the top-right corner of their cells.
+
+// mirroredDirections maps each direction to its mirror image.
+var mirroredDirections = [...]int{
+	HORIZONTAL: LEFTWARD,
+	LEFTWARD:   HORIZONTAL,
+	VERTICAL:   VERTICAL,
+	UPWARD:     UPWARD,
+	DOWN_RIGHT: DOWN_LEFT,
+	DOWN_LEFT:  DOWN_RIGHT,
+	UP_LEFT:    UP_RIGHT,
+	UP_RIGHT:   UP_LEFT,
+}
+
+// RightToLeft returns a copy of p mirrored left to right and renumbered for
+// reading right to left. Mirroring it again gives back a left-to-right
+// puzzle.
+func (p *Puzzle) RightToLeft() *Puzzle {
+	flip := func(loc Pos) Pos { return Pos{loc.R, p.Cols - 1 - loc.C} }
+	out := &Puzzle{Rows: p.Rows, Cols: p.Cols, Meta: p.Meta, grid: make(map[Pos]rune), difficulty: p.difficulty, rtl: !p.rtl}
+	for loc, ch := range p.grid {
+		out.grid[flip(loc)] = ch
+	}
+	var entries []Entry
+	for _, e := range p.entries() {
+		start := flip(Pos{e.Row, e.Col})
+		e.Row, e.Col, e.Direction = start.R, start.C, mirroredDirections[e.Direction]
+		entries = append(entries, e)
+	}
+	out.setEntries(entries)
+	for _, hl := range p.highlights {
+		cells := make([]Pos, len(hl.Cells))
+		for i, loc := range hl.Cells {
+			cells[i] = flip(loc)
+		}
+		out.Highlight(cells, hl.Colour)
+	}
+	// a bar right of a cell is right of the cell left of its mirror image
+	for loc, sides := range p.bars {
+		if sides&BAR_BELOW != 0 {
+			out.SetBar(flip(loc), BAR_BELOW)
+		}
+		if sides&BAR_RIGHT != 0 && loc.C < p.Cols-1 {
+			out.SetBar(Pos{loc.R, p.Cols - 2 - loc.C}, BAR_RIGHT)
+		}
+	}
+	for loc, kind := range p.marks {
+		out.Mark([]Pos{flip(loc)}, kind)
+	}
+	return out
+}
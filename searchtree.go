@@ -0,0 +1,102 @@
+// file: searchtree.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The search tree can be written in Graphviz DOT for looking at how the
+// backtracking search went: a root per shuffle, and under every placement
+// the placements tried on top of it. Refused placements are red and say
+// why, placements whose level was given up are grey, and the placements of
+// an accepted layout are green. Trees grow fast, so only the first Limit
+// nodes are kept.
+//
+//	crossword -dot search.dot -dot-limit 500 && dot -Tsvg search.dot > search.svg
+
+// SEARCH_TREE_LIMIT is the default number of nodes a SearchTree keeps.
+const SEARCH_TREE_LIMIT = 5000
+
+// What became of a node of the search tree.
+const (
+	NODE_TRIED    = iota // placed; nothing known yet about what followed
+	NODE_REFUSED         // failed a check, see reason
+	NODE_DEAD_END        // every placement on top of it failed
+	NODE_SOLUTION        // part of an accepted layout
+	NODE_ROOT            // a shuffle
+)
+
+// SearchTree records the placements tried by generation. Set Options.Tree to
+// collect it.
+type SearchTree struct {
+	Limit int // nodes kept; later placements are not recorded. 0 for SEARCH_TREE_LIMIT
+	nodes []treeNode
+	full  bool // placements were left out
+}
+
+// treeNode is one placement tried.
+type treeNode struct {
+	parent int // -1 for a root
+	label  string
+	state  int // a NODE_ constant
+	reason int // for NODE_REFUSED, a REJECT_ constant
+}
+
+// add records a node under parent and returns its id, or -1 if the tree is
+// nil or full or the parent was not recorded.
+func (t *SearchTree) add(parent int, label string, state, reason int) int {
+	if t == nil || parent < 0 && state != NODE_ROOT {
+		return -1
+	}
+	limit := t.Limit
+	if limit <= 0 {
+		limit = SEARCH_TREE_LIMIT
+	}
+	if len(t.nodes) >= limit {
+		t.full = true
+		return -1
+	}
+	t.nodes = append(t.nodes, treeNode{parent: parent, label: label, state: state, reason: reason})
+	return len(t.nodes) - 1
+}
+
+// mark sets the state of node id, if it was recorded.
+func (t *SearchTree) mark(id, state int) {
+	if t != nil && id >= 0 {
+		t.nodes[id].state = state
+	}
+}
+
+// writeDOT writes the tree as a Graphviz digraph.
+func (t *SearchTree) writeDOT(w io.Writer) error {
+	fmt.Fprintln(w, "digraph search {")
+	fmt.Fprintln(w, "  node [shape=box, fontname=\"Helvetica\", fontsize=10];")
+	for id, n := range t.nodes {
+		attrs := ""
+		switch n.state {
+		case NODE_ROOT:
+			attrs = ", shape=ellipse"
+		case NODE_REFUSED:
+			attrs = ", color=red, fontcolor=red"
+		case NODE_DEAD_END:
+			attrs = ", color=gray, fontcolor=gray"
+		case NODE_SOLUTION:
+			attrs = ", color=darkgreen, penwidth=2"
+		}
+		label := n.label
+		if n.state == NODE_REFUSED {
+			label += "\\n" + rejectionNames[n.reason]
+		}
+		fmt.Fprintf(w, "  n%d [label=\"%s\"%s];\n", id, strings.ReplaceAll(label, `"`, `\"`), attrs)
+		if n.parent >= 0 {
+			fmt.Fprintf(w, "  n%d -> n%d;\n", n.parent, id)
+		}
+	}
+	if t.full {
+		fmt.Fprintf(w, "  truncated [label=\"(tree cut at %d nodes)\", shape=plaintext];\n", len(t.nodes))
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
@@ -0,0 +1,110 @@
+// file: slots.go
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// A slot is a maximal run of white cells in one direction: the space a single
+// entry occupies in a grid whose block pattern is already fixed. Slots are
+// what the mini filler assigns words to and what the solve subcommand looks
+// up candidates for.
+type slot struct {
+	dir   int   // same encoding as getSequence
+	cells []Pos // cells covered by the slot, head first
+}
+
+// findSlots returns the white runs of a rows x cols pattern that are at least
+// minLen cells long, across runs first.
+func findSlots(rows, cols int, isBlock func(Pos) bool, minLen int) []slot {
+	return findBarredSlots(rows, cols, isBlock, nil, minLen)
+}
+
+// findBarredSlots is findSlots for a barred pattern: runs also end at a cell
+// for which isBarred reports a bar on the side facing the next cell (see
+// BAR_RIGHT). A nil isBarred means no bars.
+func findBarredSlots(rows, cols int, isBlock func(Pos) bool, isBarred func(Pos, int) bool, minLen int) []slot {
+	var slots []slot
+	for _, dir := range []int{HORIZONTAL, VERTICAL} {
+		lines, length := rows, cols
+		if dir == VERTICAL {
+			lines, length = cols, rows
+		}
+		for line := 0; line < lines; line++ {
+			var run []Pos
+			for i := 0; i <= length; i++ {
+				p := Pos{line, i}
+				if dir == VERTICAL {
+					p = Pos{i, line}
+				}
+				if i < length && !isBlock(p) {
+					run = append(run, p)
+					if isBarred == nil || !isBarred(p, barSide(dir)) {
+						continue
+					}
+				}
+				if len(run) > 0 && len(run) >= minLen {
+					slots = append(slots, slot{dir: dir, cells: run})
+				}
+				run = nil
+			}
+		}
+	}
+	return slots
+}
+
+// matches reports whether word fits s given the letters already in grid.
+// Cells holding '#' or missing from grid are open.
+func (s slot) matches(word string, grid map[Pos]rune) bool {
+	runes := []rune(word)
+	if len(runes) != len(s.cells) {
+		return false
+	}
+	for idx, r := range runes {
+		if existing, ok := grid[s.cells[idx]]; ok && existing != '#' && existing != r {
+			return false
+		}
+	}
+	return true
+}
+
+// pattern returns the letters of s with '?' for open cells, e.g. "A?T??S".
+func (s slot) pattern(grid map[Pos]rune) string {
+	var b strings.Builder
+	for _, loc := range s.cells {
+		if ch, ok := grid[loc]; ok && ch != '#' {
+			b.WriteRune(ch)
+		} else {
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// numberSlots returns the clue number of every slot, numbering the start
+// cells in reading order like newPuzzle does.
+func numberSlots(slots []slot) []int {
+	numbers := make(map[Pos]int)
+	var starts []Pos
+	for _, s := range slots {
+		if _, ok := numbers[s.cells[0]]; !ok {
+			numbers[s.cells[0]] = 0
+			starts = append(starts, s.cells[0])
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		if starts[i].R != starts[j].R {
+			return starts[i].R < starts[j].R
+		}
+		return starts[i].C < starts[j].C
+	})
+	for i, pos := range starts {
+		numbers[pos] = i + 1
+	}
+	out := make([]int, len(slots))
+	for i, s := range slots {
+		out[i] = numbers[s.cells[0]]
+	}
+	return out
+}
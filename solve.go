@@ -0,0 +1,271 @@
+// file: solve.go
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The solve subcommand helps finish a grid by hand: it reads a partially
+// filled grid, turns every slot into a pattern such as A?T??S and lists the
+// dictionary words that fit it. Candidates whose letters cannot be matched by
+// any candidate of a crossing slot are dropped, which usually cuts the lists
+// down a lot.
+//
+//	crossword solve -grid partial.txt -dict words.txt
+//
+// Without -dict the built-in English word list is used.
+//
+// A scored dictionary in the WORD;score format lists the best-scoring fill
+// first, and -min-score leaves out the weak entries. With -dict-cache the
+// pattern index of a big dictionary is kept in a file between runs; it is
+// rebuilt when the word list changes or the file is damaged.
+
+// runSolve is main for the solve subcommand. Returns the process exit code.
+func runSolve(args []string, w io.Writer) int {
+	fs := flag.NewFlagSet("solve", flag.ContinueOnError)
+	gridPath := fs.String("grid", "", "partially filled grid: one row per line, cells written together or separated by spaces as the grid is printed, '#' for blocks, '?', '.' or '_' for unknown cells")
+	maxCands := fs.Int("max", 10, "candidates listed per slot")
+	var df dictFlags
+	df.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
+	if *maxCands < 0 {
+		fmt.Fprintln(os.Stderr, "-max must not be negative")
+		return EXIT_USAGE
+	}
+	if *gridPath == "" {
+		fmt.Fprintln(os.Stderr, "solve needs -grid")
+		return EXIT_USAGE
+	}
+
+	rows, cols, letters, err := readGridFile(*gridPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_USAGE
+	}
+	dict, code := df.load()
+	if dict == nil {
+		return code
+	}
+
+	slots := findSlots(rows, cols, func(p Pos) bool { return letters[p] == '#' }, 2)
+	numbers := numberSlots(slots)
+	cands := solveCandidates(slots, letters, dict)
+
+	for _, dir := range []int{HORIZONTAL, VERTICAL} {
+		if dir == HORIZONTAL {
+			fmt.Fprintln(w, "Across:")
+		} else {
+			fmt.Fprintln(w, "Down:")
+		}
+		for i, s := range slots {
+			if s.dir != dir {
+				continue
+			}
+			pattern := s.pattern(letters)
+			fmt.Fprintf(w, "  %d. %s (row %d, col %d): ", numbers[i], pattern, s.cells[0].R+1, s.cells[0].C+1)
+			switch {
+			case !strings.Contains(pattern, "?") && dict.has(pattern):
+				fmt.Fprintln(w, "complete")
+			case !strings.Contains(pattern, "?"):
+				fmt.Fprintln(w, "complete, not in the dictionary")
+			case len(cands[i]) == 0:
+				fmt.Fprintln(w, "no candidates")
+			case len(cands[i]) > *maxCands:
+				fmt.Fprintf(w, "%s (+%d more)\n", strings.Join(cands[i][:*maxCands], ", "), len(cands[i])-*maxCands)
+			default:
+				fmt.Fprintln(w, strings.Join(cands[i], ", "))
+			}
+		}
+	}
+	return EXIT_OK
+}
+
+// dictFlags are the flags choosing the dictionary of solve, anagram and
+// match.
+type dictFlags struct {
+	path      string // -dict
+	lang      string // -lang
+	blocklist string // -blocklist
+	minScore  int    // -min-score
+	cache     string // -dict-cache
+}
+
+// register adds the dictionary flags to fs.
+func (df *dictFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&df.path, "dict", BUILTIN_WORDLIST, "dictionary file, one word or WORD;score per line; builtin is the built-in English list")
+	fs.StringVar(&df.blocklist, "blocklist", "", "word list of answers never to suggest")
+	fs.IntVar(&df.minScore, "min-score", 0, "with a scored WORD;score dictionary, skip entries scoring below this")
+	fs.StringVar(&df.lang, "lang", "", "language profile whose built-in list builtin names: "+strings.Join(languageNames(), " or "))
+	fs.StringVar(&df.cache, "dict-cache", "", "file keeping the dictionary's pattern index between runs")
+}
+
+// load reads the dictionary the flags choose, best-scoring words first. On
+// failure it reports the error and returns a nil dictionary and the exit
+// code.
+func (df *dictFlags) load() (*dictionary, int) {
+	if _, err := lookupLanguage(df.lang); err != nil {
+		fmt.Fprintf(os.Stderr, "-lang: %v\n", err)
+		return nil, EXIT_USAGE
+	}
+	if df.path == BUILTIN_WORDLIST && df.lang != "" {
+		df.path += ":" + df.lang
+	}
+	words, scores, err := readScoredWordFile(df.path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, EXIT_USAGE
+	}
+	// candidates are listed in dictionary order, so the best fill comes first
+	kept := words[:0]
+	for _, w := range words {
+		if scores[w] >= df.minScore {
+			kept = append(kept, w)
+		}
+	}
+	words = kept
+	sort.SliceStable(words, func(i, j int) bool { return scores[words[i]] > scores[words[j]] })
+	if df.blocklist != "" {
+		block, err := readBlocklist(df.blocklist)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, EXIT_USAGE
+		}
+		kept := words[:0]
+		for _, w := range words {
+			if !onBlocklist(w, block) {
+				kept = append(kept, w)
+			}
+		}
+		words = kept
+	}
+	dict := newDictionary(words)
+	if df.cache != "" {
+		if err := dict.useCache(df.cache); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil, EXIT_FAILURE
+		}
+	}
+	return dict, EXIT_OK
+}
+
+// useCache gives d the pattern index cached at path if it was built from the
+// same words, and otherwise, or if the cache is damaged, builds it and
+// writes the cache.
+func (d *dictionary) useCache(path string) error {
+	sum := wordsSum(d.list)
+	if f, err := os.Open(path); err == nil {
+		t, err := readTrie(f, sum, len(d.list))
+		f.Close()
+		if err == nil {
+			d.index = t
+			return nil
+		}
+		if !errors.Is(err, errStaleCache) && !errors.Is(err, errCorruptCache) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	d.index = newTrie(d.list)
+	return writeFile(path, func(w io.Writer) error { return writeTrie(w, d.index, sum) })
+}
+
+// solveCandidates returns the dictionary words fitting each slot, keeping
+// only those whose letter in every crossing cell is also offered by some
+// candidate of the crossing slot.
+func solveCandidates(slots []slot, letters map[Pos]rune, dict *dictionary) [][]string {
+	cands := make([][]string, len(slots))
+	// offered[cell][dir] holds the letters the slot in dir could put there
+	offered := make(map[Pos]map[int]map[rune]bool)
+	for i, s := range slots {
+		cands[i] = dict.match(s.pattern(letters))
+		for idx, loc := range s.cells {
+			if offered[loc] == nil {
+				offered[loc] = make(map[int]map[rune]bool)
+			}
+			set := make(map[rune]bool)
+			for _, w := range cands[i] {
+				set[[]rune(w)[idx]] = true
+			}
+			offered[loc][s.dir] = set
+		}
+	}
+
+	for i, s := range slots {
+		var kept []string
+		for _, w := range cands[i] {
+			ok := true
+			for idx, r := range []rune(w) {
+				if cross, found := offered[s.cells[idx]][1-s.dir]; found && !cross[r] {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				kept = append(kept, w)
+			}
+		}
+		cands[i] = kept
+	}
+	return cands
+}
+
+// readGridFile loads a partially filled grid. A row lists its cells either
+// together (C?T#) or separated by spaces (C ? T #), as the puzzle is
+// printed. Known letters are upper-cased; unknown cells are left out of the
+// returned map and blocks are '#'.
+func readGridFile(path string) (rows, cols int, letters map[Pos]rune, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer f.Close()
+
+	letters = make(map[Pos]rune)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		text := strings.ToUpper(strings.TrimSpace(scanner.Text()))
+		line := []rune(text)
+		if len(line) == 0 {
+			continue
+		}
+		if fields := strings.Fields(text); len(fields) > 1 {
+			line = line[:0]
+			for _, cell := range fields {
+				if len([]rune(cell)) != 1 {
+					return 0, 0, nil, fmt.Errorf("%s: row %d: write the cells together (C?T#) or each separated by a space (C ? T #)", path, rows+1)
+				}
+				line = append(line, []rune(cell)[0])
+			}
+		}
+		if rows == 0 {
+			cols = len(line)
+		} else if len(line) != cols {
+			return 0, 0, nil, fmt.Errorf("%s: row %d has %d cells, expected %d", path, rows+1, len(line), cols)
+		}
+		for c, ch := range line {
+			switch ch {
+			case '?', '.', '_':
+			default:
+				letters[Pos{rows, c}] = ch
+			}
+		}
+		rows++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if rows == 0 {
+		return 0, 0, nil, fmt.Errorf("%s: empty grid", path)
+	}
+	return rows, cols, letters, nil
+}
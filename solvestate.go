@@ -0,0 +1,216 @@
+// file: solvestate.go
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SolveState is someone's progress on a puzzle: the letters entered so far,
+// the time spent and how often they checked or revealed answers. Players
+// keep one per puzzle and save it as JSON to resume later:
+//
+//	data, _ := json.Marshal(state)
+//	...
+//	state, err := LoadSolveState(p, data)
+//
+// Checking and revealing go through the Puzzle methods in reveal.go, so
+// every player counts them the same way.
+type SolveState struct {
+	Elapsed  time.Duration // time spent solving, kept up to date by the player
+	Checks   int           // entries checked
+	Reveals  int           // letters revealed, a revealed word counting each of its letters
+	puzzle   *Puzzle
+	letters  map[Pos]rune // entered letters, upper-cased
+	revealed map[Pos]bool // cells whose letter was revealed rather than entered
+}
+
+// NewSolveState returns an empty grid for solving p.
+func NewSolveState(p *Puzzle) *SolveState {
+	return &SolveState{puzzle: p, letters: make(map[Pos]rune), revealed: make(map[Pos]bool)}
+}
+
+// Enter puts r in the cell at loc, upper-cased. A blank (space, '?', '.' or
+// '_') clears the cell.
+func (s *SolveState) Enter(loc Pos, r rune) error {
+	if s.puzzle.Cell(loc.R, loc.C) == '#' {
+		return fmt.Errorf("cell %d:%d holds no letter", loc.R+1, loc.C+1)
+	}
+	delete(s.revealed, loc)
+	if guessBlanks[r] {
+		delete(s.letters, loc)
+		return nil
+	}
+	s.letters[loc] = unicode.ToUpper(r)
+	return nil
+}
+
+// Letter returns the letter entered at loc, or 0 for an empty cell.
+func (s *SolveState) Letter(loc Pos) rune {
+	return s.letters[loc]
+}
+
+// Revealed reports whether the letter at loc was revealed.
+func (s *SolveState) Revealed(loc Pos) bool {
+	return s.revealed[loc]
+}
+
+// Guess returns the letters entered in e's cells, '?' marking empty ones.
+func (s *SolveState) Guess(e Entry) (string, error) {
+	cells, err := s.puzzle.entryCells(e)
+	if err != nil {
+		return "", err
+	}
+	guess := make([]rune, len(cells))
+	for i, loc := range cells {
+		guess[i] = '?'
+		if r, ok := s.letters[loc]; ok {
+			guess[i] = r
+		}
+	}
+	return string(guess), nil
+}
+
+// Check checks the letters entered for e as CheckGuess does, and counts it.
+func (s *SolveState) Check(e Entry) ([]int, error) {
+	guess, err := s.Guess(e)
+	if err != nil {
+		return nil, err
+	}
+	s.Checks++
+	return s.puzzle.CheckGuess(e, guess)
+}
+
+// RevealLetter fills in the letter of e at index, counted from 0, and
+// counts it.
+func (s *SolveState) RevealLetter(e Entry, index int) error {
+	r, err := s.puzzle.RevealLetter(e, index)
+	if err != nil {
+		return err
+	}
+	cells, _ := s.puzzle.entryCells(e)
+	s.reveal(cells[index], r)
+	return nil
+}
+
+// RevealWord fills in the whole of e. Letters already entered correctly are
+// not counted as revealed.
+func (s *SolveState) RevealWord(e Entry) error {
+	word, err := s.puzzle.RevealWord(e)
+	if err != nil {
+		return err
+	}
+	cells, _ := s.puzzle.entryCells(e)
+	for i, r := range []rune(word) {
+		if s.letters[cells[i]] != r {
+			s.reveal(cells[i], r)
+		}
+	}
+	return nil
+}
+
+func (s *SolveState) reveal(loc Pos, r rune) {
+	s.letters[loc] = r
+	s.revealed[loc] = true
+	s.Reveals++
+}
+
+// Solved reports whether every cell holds its right letter.
+func (s *SolveState) Solved() bool {
+	for loc, r := range s.puzzle.grid {
+		if r != '#' && s.letters[loc] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// solveStateJSON is the serialized form of a SolveState.
+type solveStateJSON struct {
+	Puzzle    string   `json:"puzzle"` // see layoutHash
+	Grid      []string `json:"grid"`   // entered letters, '.' for empty cells and '#' for blocks
+	Revealed  [][2]int `json:"revealed,omitempty"`
+	ElapsedMS int64    `json:"elapsedMs"`
+	Checks    int      `json:"checks"`
+	Reveals   int      `json:"reveals"`
+}
+
+func (s *SolveState) MarshalJSON() ([]byte, error) {
+	out := solveStateJSON{
+		Puzzle:    layoutHash(s.puzzle),
+		ElapsedMS: s.Elapsed.Milliseconds(),
+		Checks:    s.Checks,
+		Reveals:   s.Reveals,
+	}
+	for r := 0; r < s.puzzle.Rows; r++ {
+		var row strings.Builder
+		for c := 0; c < s.puzzle.Cols; c++ {
+			loc := Pos{r, c}
+			switch letter, ok := s.letters[loc]; {
+			case s.puzzle.Cell(r, c) == '#':
+				row.WriteRune('#')
+			case ok:
+				row.WriteRune(letter)
+			default:
+				row.WriteRune('.')
+			}
+			if s.revealed[loc] {
+				out.Revealed = append(out.Revealed, [2]int{r, c})
+			}
+		}
+		out.Grid = append(out.Grid, row.String())
+	}
+	return json.Marshal(out)
+}
+
+// errOtherPuzzle is returned by LoadSolveState for progress saved on
+// another puzzle.
+var errOtherPuzzle = errors.New("saved progress is for another puzzle")
+
+// LoadSolveState restores progress on p saved by marshalling a SolveState.
+// It fails with errOtherPuzzle if the progress was saved on another puzzle,
+// or on p before a Crop or RightToLeft.
+func LoadSolveState(p *Puzzle, data []byte) (*SolveState, error) {
+	var in solveStateJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, err
+	}
+	if in.Puzzle != layoutHash(p) || len(in.Grid) != p.Rows {
+		return nil, errOtherPuzzle
+	}
+	s := NewSolveState(p)
+	s.Elapsed = time.Duration(in.ElapsedMS) * time.Millisecond
+	s.Checks, s.Reveals = in.Checks, in.Reveals
+	for r, row := range in.Grid {
+		letters := []rune(row)
+		if len(letters) != p.Cols {
+			return nil, errOtherPuzzle
+		}
+		for c, letter := range letters {
+			if letter != '#' && letter != '.' {
+				if err := s.Enter(Pos{r, c}, letter); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for _, rc := range in.Revealed {
+		if loc := (Pos{rc[0], rc[1]}); s.letters[loc] != 0 {
+			s.revealed[loc] = true
+		}
+	}
+	return s, nil
+}
+
+// layoutHash identifies p's grid exactly, unlike CanonicalHash, which
+// ignores turns and mirroring that move the cells saved progress refers to.
+func layoutHash(p *Puzzle) string {
+	sum := sha256.Sum256([]byte(p.String()))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,41 @@
+// file: stats.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// GenerationStats is what a generation run did, for tuning the options on
+// large word lists: how many shuffles it took, how much searching they did
+// and how the best layout improved. Set Options.Stats to collect it.
+// Generation adds to the counts, so one value can sum several runs, e.g. the
+// sizes tried by -auto-size.
+type GenerationStats struct {
+	Iterations int           `json:"iterations"` // shuffles tried
+	Placements int           `json:"placements"` // placement attempts over all shuffles
+	Backtracks int           `json:"backtracks"` // levels given up after every word and head failed there
+	Exhausted  int           `json:"exhausted"`  // shuffles stopped by their depth budget
+	WallTime   time.Duration `json:"wallTime"`   // in nanoseconds in JSON
+	BestScores []int         `json:"bestScores"` // best intersection count after each shuffle
+}
+
+// writeStats prints s for people: the totals, and the shuffles at which the
+// best layout improved.
+func writeStats(w io.Writer, s *GenerationStats) {
+	fmt.Fprintf(w, "Generation: %d shuffles, %d placements, %d backtracks, %d out of depth, %s\n",
+		s.Iterations, s.Placements, s.Backtracks, s.Exhausted, s.WallTime.Round(time.Millisecond))
+	var steps []string
+	best := -1
+	for i, score := range s.BestScores {
+		if score != best {
+			steps = append(steps, fmt.Sprintf("%d at shuffle %d", score, i+1))
+			best = score
+		}
+	}
+	if len(steps) > 0 {
+		fmt.Fprintf(w, "Best intersections: %s\n", strings.Join(steps, ", "))
+	}
+}
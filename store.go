@@ -0,0 +1,106 @@
+// file: store.go
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A Store keeps finished puzzles for later, such as the archive behind a
+// daily puzzle. -archive picks one by a location of the form "kind:place",
+// or a bare directory path; only directories exist so far, and a database
+// or bucket store is added to storeKinds.
+
+// Store persists puzzles under IDs it chooses.
+type Store interface {
+	// Put saves p and returns its ID. Saving the same grid again on the
+	// same day replaces it.
+	Put(p *Puzzle) (string, error)
+	// Get loads the puzzle saved under id.
+	Get(id string) (*Puzzle, error)
+	// List returns the IDs of the saved puzzles, oldest day first.
+	List() ([]string, error)
+}
+
+// storeKinds maps the kind of an -archive location to the store opening it.
+var storeKinds = map[string]func(place string) (Store, error){
+	"dir": newDirStore,
+}
+
+// openStore opens the store at location, "kind:place" or a directory.
+func openStore(location string) (Store, error) {
+	if kind, place, ok := strings.Cut(location, ":"); ok {
+		if open, found := storeKinds[kind]; found {
+			return open(place)
+		}
+	}
+	return newDirStore(location)
+}
+
+// storeKindNames lists the known store kinds for help and error messages.
+func storeKindNames() []string {
+	names := make([]string, 0, len(storeKinds))
+	for name := range storeKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// STORE_EXT is the file extension of puzzles in a directory store.
+const STORE_EXT = ".ipuz"
+
+// dirStore keeps each puzzle as an ipuz file in a directory, named by the
+// day it was saved and its grid, e.g. 2024-05-01-3f9a0c2b7d1e.ipuz, so a
+// listing sorts by date.
+type dirStore struct {
+	dir string
+	now func() time.Time
+}
+
+// newDirStore opens the directory store at dir, creating the directory.
+func newDirStore(dir string) (Store, error) {
+	if dir == "" {
+		return nil, errors.New("store needs a directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &dirStore{dir: dir, now: time.Now}, nil
+}
+
+func (s *dirStore) Put(p *Puzzle) (string, error) {
+	var buf bytes.Buffer
+	if err := writeIpuz(&buf, p); err != nil {
+		return "", err
+	}
+	id := s.now().Format("2006-01-02") + "-" + layoutHash(p)[:12]
+	return id, os.WriteFile(filepath.Join(s.dir, id+STORE_EXT), buf.Bytes(), 0o644)
+}
+
+func (s *dirStore) Get(id string) (*Puzzle, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("bad puzzle ID %q", id)
+	}
+	return readPuzzleFile(filepath.Join(s.dir, id+STORE_EXT))
+}
+
+func (s *dirStore) List() ([]string, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, f := range files {
+		if id, ok := strings.CutSuffix(f.Name(), STORE_EXT); ok && !f.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,75 @@
+// file: strategy.go
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// A Strategy is a search algorithm laying out the words. The depth-first
+// search with shuffles is the only one so far; others, such as simulated
+// annealing, a beam search or a genetic algorithm, are added to strategies
+// and picked by Options.Strategy or -strategy. Code building its own binary
+// can register one of its own with RegisterStrategy from an init function.
+
+// Strategy searches for a layout of words.
+type Strategy interface {
+	// Search returns the best layout of words it finds within the grid and
+	// requirements of opts, or nil. MaxIter and MaxDepth bound the work it
+	// may do, and rng is its only source of randomness, so the same seed
+	// gives the same layout.
+	Search(words []string, opts Options, rng *rand.Rand) *Puzzle
+}
+
+// StrategyFunc adapts a function to the Strategy interface.
+type StrategyFunc func(words []string, opts Options, rng *rand.Rand) *Puzzle
+
+func (f StrategyFunc) Search(words []string, opts Options, rng *rand.Rand) *Puzzle {
+	return f(words, opts, rng)
+}
+
+var strategies = map[string]Strategy{
+	// the shuffle and backtrack search of generateTop
+	"dfs": StrategyFunc(func(words []string, opts Options, rng *rand.Rand) *Puzzle {
+		if top := generateTop(words, 1, opts, rng); len(top) > 0 {
+			return top[0]
+		}
+		return nil
+	}),
+}
+
+// newRand returns a random source for one search, seeded from the package's.
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(rand.Int63()))
+}
+
+// RegisterStrategy makes s available under name. It panics if the name is
+// empty or taken.
+func RegisterStrategy(name string, s Strategy) {
+	if name == "" || s == nil {
+		panic("RegisterStrategy: empty name or nil strategy")
+	}
+	if _, ok := strategies[name]; ok {
+		panic("RegisterStrategy: strategy " + name + " registered twice")
+	}
+	strategies[name] = s
+}
+
+// strategyNames lists the strategies for help and error messages.
+func strategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkStrategy reports an unknown strategy name; "" means dfs.
+func checkStrategy(name string) error {
+	if _, ok := strategies[name]; !ok && name != "" {
+		return fmt.Errorf("unknown strategy %q", name)
+	}
+	return nil
+}
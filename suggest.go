@@ -0,0 +1,186 @@
+// file: suggest.go
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The suggest subcommand drafts the word list of a themed puzzle: it asks a
+// related-words service for terms meaning something like the topic, keeps
+// those that fit the grid and are common enough to be fair, and prints them
+// one per line, ready for -wordfile:
+//
+//	crossword suggest -topic volcano -max-len 10 -min-freq 1 | crossword -wordfile -
+//
+// The service is a WordSuggester picked by -source; Datamuse
+// (https://www.datamuse.com/api/) is the one built in, and -endpoint points
+// it at a mirror or a compatible server.
+
+// SUGGEST_MAX is how many related words suggest asks for by default.
+const SUGGEST_MAX = 100
+
+// Suggestion is a word related to a topic.
+type Suggestion struct {
+	Word      string
+	Score     int     // relatedness as the source rates it, higher being closer
+	Frequency float64 // uses per million words of text; 0 if the source does not say
+}
+
+// WordSuggester proposes words related to a topic, closest first.
+type WordSuggester interface {
+	Suggest(topic string, max int) ([]Suggestion, error)
+}
+
+// SuggestFilter says which suggestions make usable answers. Zero fields do
+// not filter.
+type SuggestFilter struct {
+	MinLen, MaxLen int     // letters in the grid
+	MinFrequency   float64 // uses per million; rarer words are too obscure
+	Words          bool    // single words only, no phrases
+}
+
+// SuggestWords asks s for words related to topic and returns those passing
+// f, upper-cased and without duplicates, closest first.
+func SuggestWords(s WordSuggester, topic string, max int, f SuggestFilter) ([]string, error) {
+	suggestions, err := s.Suggest(topic, max)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Score > suggestions[j].Score })
+	seen := make(map[string]bool)
+	var words []string
+	for _, sg := range suggestions {
+		word := strings.ToUpper(strings.TrimSpace(sg.Word))
+		n := len([]rune(gridForm(word, nil)))
+		switch {
+		case word == "" || seen[word] || strings.EqualFold(word, topic):
+		case f.MinLen > 0 && n < f.MinLen, f.MaxLen > 0 && n > f.MaxLen:
+		case f.MinFrequency > 0 && sg.Frequency < f.MinFrequency:
+		case f.Words && strings.ContainsFunc(word, isWordBreak):
+		default:
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words, nil
+}
+
+// suggestSources maps the -source names to their suggesters, built for an
+// endpoint URL ("" for the source's own).
+var suggestSources = map[string]func(endpoint string) WordSuggester{
+	"datamuse": func(endpoint string) WordSuggester {
+		if endpoint == "" {
+			endpoint = DATAMUSE_ENDPOINT
+		}
+		return &datamuse{endpoint: endpoint, client: &http.Client{Timeout: WORDLIST_TIMEOUT}}
+	},
+}
+
+// DATAMUSE_ENDPOINT is the words route of the public Datamuse API.
+const DATAMUSE_ENDPOINT = "https://api.datamuse.com/words"
+
+// datamuse asks the Datamuse API for words with a meaning like the topic.
+type datamuse struct {
+	endpoint string
+	client   *http.Client
+}
+
+// datamuseWord is one result of the words route. With md=f the tags hold
+// the frequency as "f:12.34".
+type datamuseWord struct {
+	Word  string   `json:"word"`
+	Score int      `json:"score"`
+	Tags  []string `json:"tags"`
+}
+
+func (d *datamuse) Suggest(topic string, max int) ([]Suggestion, error) {
+	query := url.Values{"ml": {topic}, "max": {strconv.Itoa(max)}, "md": {"f"}}
+	resp, err := d.client.Get(d.endpoint + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", d.endpoint, resp.Status)
+	}
+	var results []datamuseWord
+	if err := json.NewDecoder(io.LimitReader(resp.Body, WORDLIST_MAX_BYTES)).Decode(&results); err != nil {
+		return nil, fmt.Errorf("%s: %w", d.endpoint, err)
+	}
+	suggestions := make([]Suggestion, len(results))
+	for i, r := range results {
+		suggestions[i] = Suggestion{Word: r.Word, Score: r.Score}
+		for _, tag := range r.Tags {
+			if f, ok := strings.CutPrefix(tag, "f:"); ok {
+				suggestions[i].Frequency, _ = strconv.ParseFloat(f, 64)
+			}
+		}
+	}
+	return suggestions, nil
+}
+
+// suggestSourceNames lists the -source choices for help and error messages.
+func suggestSourceNames() []string {
+	names := make([]string, 0, len(suggestSources))
+	for name := range suggestSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runSuggest is main for the suggest subcommand. Returns the process exit
+// code.
+func runSuggest(args []string, w io.Writer) int {
+	fs := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	topic := fs.String("topic", "", "topic word or phrase to find related words for")
+	source := fs.String("source", "datamuse", "related-words service: "+strings.Join(suggestSourceNames(), " or "))
+	endpoint := fs.String("endpoint", "", "URL of the service, for a mirror or compatible server (default the service's own)")
+	max := fs.Int("max", SUGGEST_MAX, "related words to ask for, before filtering")
+	var f SuggestFilter
+	fs.IntVar(&f.MinLen, "min-len", 3, "drop words with fewer letters than this (0 = no limit)")
+	fs.IntVar(&f.MaxLen, "max-len", 0, "drop words with more letters than this, e.g. the grid size (0 = no limit)")
+	fs.Float64Var(&f.MinFrequency, "min-freq", 0, "drop words used less than this many times per million words, as too obscure (0 = no limit)")
+	fs.BoolVar(&f.Words, "single", false, "drop phrases, keeping single words")
+	if err := fs.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
+	if *topic == "" {
+		fmt.Fprintln(os.Stderr, "suggest needs -topic")
+		return EXIT_USAGE
+	}
+	newSuggester, ok := suggestSources[*source]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown -source %q (want %s)\n", *source, strings.Join(suggestSourceNames(), " or "))
+		return EXIT_USAGE
+	}
+	if *max < 1 {
+		fmt.Fprintln(os.Stderr, "-max must be positive")
+		return EXIT_USAGE
+	}
+
+	words, err := SuggestWords(newSuggester(*endpoint), *topic, *max, f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_FAILURE
+	}
+	if len(words) == 0 {
+		fmt.Fprintf(os.Stderr, "no related words for %q passed the filters\n", *topic)
+		return EXIT_NO_PUZZLE
+	}
+	for _, word := range words {
+		fmt.Fprintln(w, word)
+	}
+	return EXIT_OK
+}
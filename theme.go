@@ -0,0 +1,131 @@
+// file: theme.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Theme is the look of a drawn puzzle: fonts, colours, cell size, where the
+// clue numbers go and how blocks are drawn. Colours are "#rrggbb" and sizes
+// are in points. Only the PDF output draws puzzles in this tree; the text
+// grids keep their terminal look.
+type Theme struct {
+	Font       string  `json:"font"`       // font family: Helvetica, Times or Courier
+	CJKFont    string  `json:"cjkFont"`    // Asian font for CJK text, see pdfCJKFonts; STSong-Light if empty
+	LetterSize float64 `json:"letterSize"` // solution letters as a fraction of the cell; 0 for 0.6
+	Ink        string  `json:"ink"`        // lines, letters and numbers
+	Paper      string  `json:"paper"`      // open cells
+	Block      string  `json:"block"`      // blocks
+	Shade      string  `json:"shade"`      // shaded cells
+	CellSize   float64 `json:"cellSize"`   // largest cell; grids shrink to fit the page
+	Stroke     float64 `json:"stroke"`     // cell outlines; bars are six times as wide
+	Numbers    string  `json:"numbers"`    // clue number corner: top-left or top-right
+	BlockStyle string  `json:"blockStyle"` // solid or hatched
+}
+
+// themes are the built-in -theme choices.
+var themes = map[string]Theme{
+	"classic": {Font: "Helvetica", Ink: "#000000", Paper: "#FFFFFF", Block: "#000000", Shade: SHADE_COLOUR,
+		CellSize: 28, Stroke: 0.5, Numbers: "top-left", BlockStyle: "solid"},
+	"newsprint": {Font: "Times", Ink: "#1A1A1A", Paper: "#FFFFFF", Block: "#1A1A1A", Shade: "#C8C8C8",
+		CellSize: 26, Stroke: 0.75, Numbers: "top-left", BlockStyle: "solid"},
+	"high-contrast": {Font: "Helvetica", Ink: "#000000", Paper: "#FFFFFF", Block: "#000000", Shade: "#A0A0A0",
+		CellSize: 48, Stroke: 1.5, Numbers: "top-left", BlockStyle: "solid"},
+	"ink-saver": {Font: "Helvetica", Ink: "#000000", Paper: "#FFFFFF", Block: "#808080", Shade: "#E8E8E8",
+		CellSize: 28, Stroke: 0.5, Numbers: "top-left", BlockStyle: "hatched"},
+}
+
+// themeNames lists the built-in themes for help and error messages.
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadTheme returns the built-in theme called name, or reads a theme from
+// name if it is a .json file. Keys the file leaves out keep their classic
+// values.
+func loadTheme(name string) (Theme, error) {
+	if !strings.HasSuffix(strings.ToLower(name), ".json") {
+		t, ok := themes[name]
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown theme %q (want %s, or a .json file)", name, strings.Join(themeNames(), ", "))
+		}
+		return t, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return Theme{}, err
+	}
+	t := themes["classic"]
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := t.check(); err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return t, nil
+}
+
+// check reports the first setting of t that cannot be drawn.
+func (t Theme) check() error {
+	if _, ok := pdfFontFamilies[t.Font]; !ok {
+		return fmt.Errorf("unknown font %q (want Helvetica, Times or Courier)", t.Font)
+	}
+	if _, ok := pdfCJKFonts[t.CJKFont]; !ok && t.CJKFont != "" {
+		return fmt.Errorf("unknown cjkFont %q (want %s)", t.CJKFont, strings.Join(cjkFontNames(), ", "))
+	}
+	if t.LetterSize < 0 || t.LetterSize > 1 {
+		return fmt.Errorf("letterSize must be between 0 and 1")
+	}
+	for _, colour := range []string{t.Ink, t.Paper, t.Block, t.Shade} {
+		if _, err := parseColour(colour); err != nil {
+			return err
+		}
+	}
+	if t.CellSize <= 0 || t.Stroke <= 0 {
+		return fmt.Errorf("cellSize and stroke must be positive")
+	}
+	if t.Numbers != "top-left" && t.Numbers != "top-right" {
+		return fmt.Errorf("unknown numbers %q (want top-left or top-right)", t.Numbers)
+	}
+	if t.BlockStyle != "solid" && t.BlockStyle != "hatched" {
+		return fmt.Errorf("unknown blockStyle %q (want solid or hatched)", t.BlockStyle)
+	}
+	return nil
+}
+
+// letterSize returns the size of the solution letters as a fraction of the
+// cell.
+func (t Theme) letterSize() float64 {
+	if t.LetterSize == 0 {
+		return 0.6
+	}
+	return t.LetterSize
+}
+
+// cjkFontNames lists the Asian fonts a theme may name.
+func cjkFontNames() []string {
+	names := make([]string, 0, len(pdfCJKFonts))
+	for name := range pdfCJKFonts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseColour reads a "#rrggbb" colour as red, green and blue from 0 to 1.
+func parseColour(s string) ([3]float64, error) {
+	var r, g, b int
+	if n, _ := fmt.Sscanf(strings.ToLower(s), "#%02x%02x%02x", &r, &g, &b); n != 3 || len(s) != 7 {
+		return [3]float64{}, fmt.Errorf("bad colour %q (want #rrggbb)", s)
+	}
+	return [3]float64{float64(r) / 255, float64(g) / 255, float64(b) / 255}, nil
+}
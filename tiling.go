@@ -0,0 +1,200 @@
+// file: tiling.go
+package main
+
+import (
+	"log/slog"
+	"math/rand"
+)
+
+// Giant grids (puzzle-book spreads) are too large for a single recursive
+// search: every head on the empty grid is a branch and the maps grow with the
+// square of the size. Instead the grid is cut into overlapping tiles that are
+// filled one after the other. Each tile is seeded with whatever earlier tiles
+// left in its area, so new words cross the existing ones, and every tile result
+// is re-checked against the full grid before it is stitched in.
+
+const (
+	GIANT_GRID_SIZE = 50 // grids at least this large are filled tile by tile
+	TILE_SIZE       = 15 // default tile edge; grown to fit the longest word
+	TILE_OVERLAP    = 4  // rows/columns shared by neighbouring tiles
+	TILE_ITER       = 50 // shuffles tried per tile before giving up on a word
+)
+
+// tilePlacement is a placed word in absolute grid coordinates.
+type tilePlacement struct {
+	head      Pos
+	direction int
+	word      string
+}
+
+// generateTiled fills a gridSize x gridSize grid tile by tile. Words that no
+// tile could take are retried one at a time against the stitched grid, and
+// those that still do not fit are logged and left out.
+func generateTiled(words []string, gridSize, tileSize, overlap, maxDepth int, display map[string]string, rng *rand.Rand, logger *slog.Logger) *Puzzle {
+	for _, w := range words {
+		if l := len([]rune(w)); l > tileSize {
+			tileSize = l
+		}
+	}
+	if tileSize > gridSize {
+		tileSize = gridSize
+	}
+	step := tileSize - overlap
+	if step < 1 {
+		step = 1
+	}
+
+	var origins []Pos
+	for r := 0; ; r += step {
+		if r+tileSize > gridSize {
+			r = gridSize - tileSize
+		}
+		for c := 0; ; c += step {
+			if c+tileSize > gridSize {
+				c = gridSize - tileSize
+			}
+			origins = append(origins, Pos{r, c})
+			if c+tileSize >= gridSize {
+				break
+			}
+		}
+		if r+tileSize >= gridSize {
+			break
+		}
+	}
+
+	// deal words round-robin (longest first) so every tile gets a mix of lengths
+	perTile := make([][]string, len(origins))
+	for i, w := range words {
+		perTile[i%len(origins)] = append(perTile[i%len(origins)], w)
+	}
+
+	grid := initGrid(gridSize)
+	cellDir := initCellDir(gridSize)
+	index := newGridIndex()
+	var placed []tilePlacement
+	var leftover []string
+
+	for t, origin := range origins {
+		tileWords := append(leftover, perTile[t]...)
+		leftover = nil
+		// push words on to later tiles until this one can be filled and stitched
+		for len(tileWords) > 0 {
+			added, ok := fillTile(origin, tileSize, tileWords, placed, maxDepth, rng)
+			if ok && stitchTile(added, gridSize, grid, cellDir, index) {
+				placed = append(placed, added...)
+				break
+			}
+			leftover = append(leftover, tileWords[0])
+			tileWords = tileWords[1:]
+		}
+	}
+
+	// last resort for words no tile could take: single placements on the full grid
+	single, missed := placeSingly(leftover, gridSize, grid, cellDir, index)
+	placed = append(placed, single...)
+	for _, w := range missed {
+		written := w
+		if d, ok := display[w]; ok {
+			written = d
+		}
+		logger.Warn("word does not fit the giant grid", "word", written)
+	}
+
+	classification := map[int][]Placement{HORIZONTAL: {}, VERTICAL: {}}
+	for i, p := range placed {
+		classification[p.direction] = append(classification[p.direction], Placement{Loc: gridSize*p.head.R + p.head.C, Word: p.word, Order: i})
+	}
+	return newPuzzle(gridSize, grid, classification, display)
+}
+
+// fillTile runs the regular backtracker on one tile, seeded with the parts of
+// already placed words that fall inside it. The new placements are returned in
+// absolute coordinates and in the order they were made.
+func fillTile(origin Pos, tileSize int, words []string, placed []tilePlacement, maxDepth int, rng *rand.Rand) ([]tilePlacement, bool) {
+	for iter := 0; iter < TILE_ITER; iter++ {
+		shuffled := make([]string, len(words))
+		copy(shuffled, words)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+		search := newSearch(shuffled, tileSize, nil, maxDepth, 0)
+		seedTile(origin, tileSize, placed, search.grid, search.cellDirection, search.index)
+		if search.run(0); !search.accepted {
+			continue
+		}
+
+		classification := search.classification()
+		added := make([]tilePlacement, len(classification[HORIZONTAL])+len(classification[VERTICAL]))
+		for dir, list := range classification {
+			for _, p := range list {
+				head := Pos{origin.R + p.Loc/tileSize, origin.C + p.Loc%tileSize}
+				added[p.Order] = tilePlacement{head, dir, p.Word}
+			}
+		}
+		return added, true
+	}
+	return nil, false
+}
+
+// placeSingly puts each word at the first acceptable place crossing the grid,
+// without backtracking. Returns the placements and the words that did not fit.
+func placeSingly(words []string, gridSize int, grid map[Pos]rune, cellDir map[Pos]string, index *gridIndex) ([]tilePlacement, []string) {
+	var placed []tilePlacement
+	var missed []string
+	for _, w := range words {
+		found := false
+	search:
+		for _, dir := range []int{HORIZONTAL, VERTICAL} {
+			for _, head := range intersectingHead(w, dir, cellDir, index) {
+				seq := getSequence(head, dir, w)
+				if isAcceptable(w, seq, dir, grid, cellDir, gridSize, index) {
+					addToGrid(w, seq, dir, grid, cellDir, index)
+					placed = append(placed, tilePlacement{head, dir, w})
+					found = true
+					break search
+				}
+			}
+		}
+		if !found {
+			missed = append(missed, w)
+		}
+	}
+	return placed, missed
+}
+
+// seedTile copies the in-tile part of every placed word into the tile's maps.
+func seedTile(origin Pos, tileSize int, placed []tilePlacement, grid map[Pos]rune, cellDir map[Pos]string, index *gridIndex) {
+	for _, p := range placed {
+		runes := []rune(p.word)
+		var localWord []rune
+		var localSeq []Pos
+		for i, loc := range getSequence(p.head, p.direction, p.word) {
+			local := Pos{loc.R - origin.R, loc.C - origin.C}
+			if local.R < 0 || local.R >= tileSize || local.C < 0 || local.C >= tileSize {
+				continue
+			}
+			localWord = append(localWord, runes[i])
+			localSeq = append(localSeq, local)
+		}
+		if len(localSeq) > 0 {
+			addToGrid(string(localWord), localSeq, p.direction, grid, cellDir, index)
+		}
+	}
+}
+
+// stitchTile adds a tile's placements to the full grid, checking each against
+// everything outside the tile as well. On any conflict the tile is rolled back.
+func stitchTile(added []tilePlacement, gridSize int, grid map[Pos]rune, cellDir map[Pos]string, index *gridIndex) bool {
+	for i, p := range added {
+		seq := getSequence(p.head, p.direction, p.word)
+		if !isAcceptable(p.word, seq, p.direction, grid, cellDir, gridSize, index) {
+			for j := i - 1; j >= 0; j-- {
+				q := added[j]
+				removeFromGrid(q.word, getSequence(q.head, q.direction, q.word), q.direction, grid, cellDir, index)
+			}
+			return false
+		}
+		addToGrid(p.word, seq, p.direction, grid, cellDir, index)
+	}
+	return true
+}
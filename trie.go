@@ -0,0 +1,210 @@
+// file: trie.go
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+)
+
+// Pattern lookups such as ??A??N walk a letter trie instead of testing every
+// word of the length, following one edge at a known letter and all of them
+// at a '?'. Each node also knows at which depths below it words end, so
+// branches holding no word of the pattern's length are never entered. The
+// terminal nodes keep the index of their word, which lets matches come out
+// in dictionary order (best score first for scored lists); that is also why
+// the trie is not minimised into a DAWG, where words would share their
+// final nodes.
+//
+// The trie lives in flat arrays, so it can be cached in a binary file and
+// read back without rebuilding it.
+
+// trie is a letter trie over a word list. Node 0 is the root.
+type trie struct {
+	first  []int32  // node i's edges are first[i]:first[i+1]
+	labels []int32  // edge letters, sorted within each node
+	next   []int32  // edge targets
+	word   []int32  // per node, the index of the word ending there, or -1
+	reach  []uint64 // per node, bit k set if a word ends k letters below; 63 stands for 63 or more
+}
+
+// newTrie builds the trie of words.
+func newTrie(words []string) *trie {
+	type node struct {
+		children map[rune]*node
+		word     int32
+	}
+	root := &node{word: -1}
+	count := 1
+	for i, w := range words {
+		n := root
+		for _, r := range w {
+			child, ok := n.children[r]
+			if !ok {
+				if n.children == nil {
+					n.children = make(map[rune]*node)
+				}
+				child = &node{word: -1}
+				n.children[r] = child
+				count++
+			}
+			n = child
+		}
+		if n.word < 0 {
+			n.word = int32(i)
+		}
+	}
+
+	// number the nodes breadth first, so every node's edges are contiguous
+	t := &trie{first: make([]int32, 0, count+1), word: make([]int32, 0, count), reach: make([]uint64, count)}
+	order := []*node{root}
+	for i := 0; i < len(order); i++ {
+		n := order[i]
+		t.first = append(t.first, int32(len(t.labels)))
+		t.word = append(t.word, n.word)
+		letters := make([]rune, 0, len(n.children))
+		for r := range n.children {
+			letters = append(letters, r)
+		}
+		sort.Slice(letters, func(a, b int) bool { return letters[a] < letters[b] })
+		for _, r := range letters {
+			t.labels = append(t.labels, int32(r))
+			t.next = append(t.next, int32(len(order)))
+			order = append(order, n.children[r])
+		}
+	}
+	t.first = append(t.first, int32(len(t.labels)))
+	// children come after their parents, so a backward pass sees them first
+	for i := len(order) - 1; i >= 0; i-- {
+		if t.word[i] >= 0 {
+			t.reach[i] |= 1
+		}
+		for e := t.first[i]; e < t.first[i+1]; e++ {
+			below := t.reach[t.next[e]]
+			t.reach[i] |= below<<1 | below&(1<<63)
+		}
+	}
+	return t
+}
+
+// match calls visit with the index of every word fitting pattern, where '?'
+// stands for any letter, in no particular order.
+func (t *trie) match(pattern []rune, visit func(word int)) {
+	t.walk(0, pattern, visit)
+}
+
+func (t *trie) walk(node int32, rest []rune, visit func(word int)) {
+	if len(rest) == 0 {
+		if t.word[node] >= 0 {
+			visit(int(t.word[node]))
+		}
+		return
+	}
+	if t.reach[node]&(1<<min(len(rest), 63)) == 0 {
+		return
+	}
+	edges := t.labels[t.first[node]:t.first[node+1]]
+	if rest[0] == '?' {
+		for i := range edges {
+			t.walk(t.next[int(t.first[node])+i], rest[1:], visit)
+		}
+		return
+	}
+	if i := sort.Search(len(edges), func(i int) bool { return edges[i] >= int32(rest[0]) }); i < len(edges) && edges[i] == int32(rest[0]) {
+		t.walk(t.next[int(t.first[node])+i], rest[1:], visit)
+	}
+}
+
+// TRIE_MAGIC starts a trie cache file.
+const TRIE_MAGIC = "CWTRIE1\n"
+
+// wordsSum identifies a word list, so a cache built from another list is
+// not used.
+func wordsSum(words []string) uint64 {
+	h := fnv.New64a()
+	for _, w := range words {
+		io.WriteString(h, w)
+		h.Write([]byte{'\n'})
+	}
+	return h.Sum64()
+}
+
+// writeTrie writes t, built from the word list with the given sum, to w.
+func writeTrie(w io.Writer, t *trie, sum uint64) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(TRIE_MAGIC)
+	for _, v := range []any{sum, uint32(len(t.word)), uint32(len(t.labels)), t.first, t.labels, t.next, t.word, t.reach} {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// errStaleCache reports a trie cache built from another word list.
+var errStaleCache = errors.New("cache was built from another word list")
+
+// errCorruptCache reports a trie cache that is cut short or whose arrays do
+// not make a trie over the word list.
+var errCorruptCache = errors.New("corrupt trie cache")
+
+// readTrie reads a trie written by writeTrie, failing with errStaleCache if
+// it was built from a word list with another sum, and with errCorruptCache
+// if its arrays cannot be a trie over that many words.
+func readTrie(r io.Reader, sum uint64, words int) (*trie, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(TRIE_MAGIC))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != TRIE_MAGIC {
+		return nil, errors.New("not a trie cache file")
+	}
+	var header struct {
+		Sum          uint64
+		Nodes, Edges uint32
+	}
+	if err := binary.Read(br, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorruptCache, err)
+	}
+	if header.Sum != sum {
+		return nil, errStaleCache
+	}
+	if header.Nodes == 0 || header.Nodes > 1<<28 || header.Edges != header.Nodes-1 {
+		return nil, fmt.Errorf("%w (%d nodes, %d edges)", errCorruptCache, header.Nodes, header.Edges)
+	}
+	t := &trie{
+		first:  make([]int32, header.Nodes+1),
+		labels: make([]int32, header.Edges),
+		next:   make([]int32, header.Edges),
+		word:   make([]int32, header.Nodes),
+		reach:  make([]uint64, header.Nodes),
+	}
+	for _, v := range []any{t.first, t.labels, t.next, t.word, t.reach} {
+		if err := binary.Read(br, binary.LittleEndian, v); err != nil {
+			return nil, fmt.Errorf("%w: %v", errCorruptCache, err)
+		}
+	}
+
+	// walk indexes the arrays with these, so a damaged file must not get
+	// past here
+	nodes, edges := int32(header.Nodes), int32(header.Edges)
+	if t.first[0] != 0 || t.first[nodes] != edges {
+		return nil, fmt.Errorf("%w: edges do not add up", errCorruptCache)
+	}
+	for i := int32(0); i < nodes; i++ {
+		if t.first[i+1] < t.first[i] {
+			return nil, fmt.Errorf("%w: node %d has its edges out of order", errCorruptCache, i)
+		}
+		if t.word[i] < -1 || t.word[i] >= int32(words) {
+			return nil, fmt.Errorf("%w: node %d ends word %d of %d", errCorruptCache, i, t.word[i], words)
+		}
+	}
+	for e, child := range t.next {
+		if child < 1 || child >= nodes {
+			return nil, fmt.Errorf("%w: edge %d leads to node %d of %d", errCorruptCache, e, child, nodes)
+		}
+	}
+	return t, nil
+}
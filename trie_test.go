@@ -0,0 +1,107 @@
+// file: trie_test.go
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// trieMatches returns the words of list fitting pattern, sorted.
+func trieMatches(t *trie, list []string, pattern string) []string {
+	var words []string
+	t.match([]rune(pattern), func(i int) { words = append(words, list[i]) })
+	sort.Strings(words)
+	return words
+}
+
+func TestTrieCacheRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		words    []string
+		patterns []string
+	}{
+		{"empty list", nil, []string{"?", "???"}},
+		{"one word", []string{"CAT"}, []string{"CAT", "C?T", "??", "????"}},
+		{"shared prefixes", []string{"CAT", "CATS", "CAR", "COT", "DOG"}, []string{"C??", "CA??", "?O?", "???S", "D??"}},
+		{"accented", []string{"ÉCLAIR", "ÑANDÚ", "ECLAIR"}, []string{"?CLAIR", "Ñ????", "??????"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			built := newTrie(tt.words)
+			sum := wordsSum(tt.words)
+			var b bytes.Buffer
+			if err := writeTrie(&b, built, sum); err != nil {
+				t.Fatal(err)
+			}
+			written := bytes.Clone(b.Bytes())
+			read, err := readTrie(&b, sum, len(tt.words))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var again bytes.Buffer
+			if err := writeTrie(&again, read, sum); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(again.Bytes(), written) {
+				t.Errorf("read back a different trie")
+			}
+			for _, pattern := range tt.patterns {
+				if got, want := trieMatches(read, tt.words, pattern), trieMatches(built, tt.words, pattern); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: %v, want %v", pattern, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadTrieRefuses(t *testing.T) {
+	words := []string{"CAT", "CATS", "COT"}
+	sum := wordsSum(words)
+	tests := []struct {
+		name   string
+		damage func(t *trie)            // applied before writing; nil for none
+		cut    func(data []byte) []byte // applied to the written file; nil for none
+		sum    uint64
+		want   error // nil for any error other than these two
+	}{
+		{"another word list", nil, nil, sum + 1, errStaleCache},
+		{"cut short", nil, func(data []byte) []byte { return data[:len(data)-5] }, sum, errCorruptCache},
+		{"no header", nil, func(data []byte) []byte { return data[:len(TRIE_MAGIC)+3] }, sum, errCorruptCache},
+		{"edge to no node", func(t *trie) { t.next[0] = int32(len(t.word)) }, nil, sum, errCorruptCache},
+		{"edge to the root", func(t *trie) { t.next[0] = 0 }, nil, sum, errCorruptCache},
+		{"word index too large", func(t *trie) { t.word[len(t.word)-1] = int32(len(words)) }, nil, sum, errCorruptCache},
+		{"negative word index", func(t *trie) { t.word[0] = -2 }, nil, sum, errCorruptCache},
+		{"edges out of order", func(t *trie) { t.first[1], t.first[2] = t.first[2], t.first[1]+5 }, nil, sum, errCorruptCache},
+		{"edge count off", func(t *trie) { t.first[len(t.first)-1]++ }, nil, sum, errCorruptCache},
+		{"not a cache file", nil, func(data []byte) []byte { return []byte(strings.Repeat("CAT\n", 20)) }, sum, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTrie(words)
+			if tt.damage != nil {
+				tt.damage(tr)
+			}
+			var b bytes.Buffer
+			if err := writeTrie(&b, tr, sum); err != nil {
+				t.Fatal(err)
+			}
+			data := b.Bytes()
+			if tt.cut != nil {
+				data = tt.cut(data)
+			}
+			_, err := readTrie(bytes.NewReader(data), tt.sum, len(words))
+			switch {
+			case err == nil:
+				t.Fatal("read the cache")
+			case tt.want != nil && !errors.Is(err, tt.want):
+				t.Errorf("error %v, want %v", err, tt.want)
+			case tt.want == nil && (errors.Is(err, errStaleCache) || errors.Is(err, errCorruptCache)):
+				t.Errorf("error %v would have the cache overwritten", err)
+			}
+		})
+	}
+}
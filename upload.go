@@ -0,0 +1,202 @@
+// file: upload.go
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// -upload publishes every puzzle written to an object-store bucket, for
+// batch runs feeding a website straight from the bucket:
+//
+//	crossword -count 7 -export ipuz -upload 's3://puzzles/daily/{date}-{n}{ext}'
+//
+// The key is a template: {date} is the day of the run (2024-05-01), {id}
+// the puzzle's ID as the -archive directory store names it, {n} its number
+// in the batch and {ext} the extension of the written file. s3:// talks to
+// Amazon S3, gs:// to Google Cloud Storage through its S3-compatible API;
+// both sign requests with the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+// (an HMAC key for Cloud Storage), plus AWS_SESSION_TOKEN if set.
+// AWS_REGION picks the S3 region, and AWS_ENDPOINT_URL points either at
+// another compatible server such as MinIO.
+
+// UPLOAD_TIMEOUT bounds one upload.
+const UPLOAD_TIMEOUT = 60 * time.Second
+
+// uploadEndpoints are the default endpoints of the -upload schemes.
+var uploadEndpoints = map[string]string{
+	"s3": "https://s3.{region}.amazonaws.com",
+	"gs": "https://storage.googleapis.com",
+}
+
+// uploader puts files in a bucket with S3 PUT Object requests, signed with
+// AWS Signature Version 4.
+type uploader struct {
+	endpoint string // scheme and host, requests use path-style URLs
+	bucket   string
+	key      string // template, see expandKey
+	region   string
+	keyID    string
+	secret   string
+	token    string // session token, for temporary credentials
+	client   *http.Client
+	now      func() time.Time
+}
+
+// newUploader parses an -upload target, taking credentials from the
+// environment.
+func newUploader(target string) (*uploader, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, ok := uploadEndpoints[u.Scheme]
+	if !ok || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("upload target %q is not s3://bucket/key or gs://bucket/key", target)
+	}
+	up := &uploader{
+		bucket: u.Host,
+		key:    strings.TrimPrefix(u.Path, "/"),
+		region: os.Getenv("AWS_REGION"),
+		keyID:  os.Getenv("AWS_ACCESS_KEY_ID"),
+		secret: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:  os.Getenv("AWS_SESSION_TOKEN"),
+		client: &http.Client{Timeout: UPLOAD_TIMEOUT},
+		now:    time.Now,
+	}
+	if up.keyID == "" || up.secret == "" {
+		return nil, errors.New("uploading needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if up.region == "" {
+		up.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if up.region == "" {
+		up.region = "us-east-1"
+		if u.Scheme == "gs" {
+			up.region = "auto"
+		}
+	}
+	up.endpoint = strings.ReplaceAll(endpoint, "{region}", up.region)
+	if custom := os.Getenv("AWS_ENDPOINT_URL"); custom != "" {
+		up.endpoint = strings.TrimSuffix(custom, "/")
+	}
+	return up, nil
+}
+
+// expandKey fills in the key template for puzzle n (from 1) of the batch.
+func (up *uploader) expandKey(p *Puzzle, n int, ext string) string {
+	return strings.NewReplacer(
+		"{date}", up.now().Format("2006-01-02"),
+		"{id}", layoutHash(p)[:12],
+		"{n}", strconv.Itoa(n),
+		"{ext}", ext,
+	).Replace(up.key)
+}
+
+// upload puts data in the bucket under key, returning the object's URL.
+func (up *uploader) upload(key string, data []byte) (string, error) {
+	objectURL := up.endpoint + "/" + up.bucket + "/" + awsEscapePath(key)
+	req, err := http.NewRequest(http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", contentType)
+	up.sign(req, data)
+	resp, err := up.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("upload %s: %s: %s", key, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return objectURL, nil
+}
+
+// sign adds the AWS Signature Version 4 headers for body to req.
+func (up *uploader) sign(req *http.Request, body []byte) {
+	now := up.now().UTC()
+	stamp, day := now.Format("20060102T150405Z"), now.Format("20060102")
+	payload := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+	if up.token != "" {
+		req.Header.Set("X-Amz-Security-Token", up.token)
+	}
+
+	// the headers set so far are signed, in lower-cased name order; the
+	// client adds only unsigned ones
+	names := []string{"host"}
+	values := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		names = append(names, lower)
+		values[lower] = strings.TrimSpace(req.Header.Get(name))
+	}
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(name + ":" + values[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		signed,
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+
+	scope := day + "/" + up.region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+	key := []byte("AWS4" + up.secret)
+	for _, part := range []string{day, up.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", up.keyID, scope, signed, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscapePath escapes an object key as S3 signs it: every byte but the
+// unreserved characters and '/' is percent-encoded.
+func awsEscapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '.', ch == '_', ch == '~', ch == '/':
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+// file: validate.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of Violation reported by Validate.
+const (
+	VIOLATION_CROSSING     = "crossing"     // an entry disagrees with the letter in the grid
+	VIOLATION_ADJACENCY    = "adjacency"    // letters touch without forming an entry
+	VIOLATION_UNLISTED     = "unlisted"     // an entry is not in the word list
+	VIOLATION_DUPLICATE    = "duplicate"    // the same answer appears twice
+	VIOLATION_DISCONNECTED = "disconnected" // letters not joined to the largest group
+)
+
+// Violation is one rule a grid breaks, with the cells involved (0-based).
+type Violation struct {
+	Kind    string
+	Cells   []Pos
+	Message string
+}
+
+func (v Violation) String() string {
+	if len(v.Cells) == 0 {
+		return v.Kind + ": " + v.Message
+	}
+	return fmt.Sprintf("%s at (%d, %d): %s", v.Kind, v.Cells[0].R+1, v.Cells[0].C+1, v.Message)
+}
+
+// Validate checks p against the rules the generator keeps: entries agree
+// with the grid where they cross, every run of two or more letters is an
+// entry and every entry is a whole run, answers are distinct, and all
+// letters form one connected group. With a non-nil words, every answer must
+// also be one of them (ignoring case). It is meant for imported and edited
+// puzzles, and as a sanity check on generated ones.
+func Validate(p *Puzzle, words []string) []Violation {
+	var out []Violation
+	entries := p.entries()
+
+	for _, e := range entries {
+		runes := []rune(e.Word)
+		for i, loc := range getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word) {
+			if ch := p.Cell(loc.R, loc.C); ch != runes[i] {
+				out = append(out, Violation{VIOLATION_CROSSING, []Pos{loc},
+					fmt.Sprintf("%s needs %c but the grid has %c", e.Display, runes[i], ch)})
+			}
+		}
+	}
+
+	// entries and letter runs must match one to one
+	type run struct {
+		head      Pos
+		direction int
+		length    int
+	}
+	// reversed entries cover the run read the other way; diagonal ones
+	// make no runs, since the generator keeps them from touching sideways
+	entryRun := func(e Entry) (run, bool) {
+		cells := getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word)
+		switch e.Direction {
+		case HORIZONTAL, VERTICAL:
+			return run{cells[0], e.Direction, len(cells)}, true
+		case LEFTWARD:
+			return run{cells[len(cells)-1], HORIZONTAL, len(cells)}, true
+		case UPWARD:
+			return run{cells[len(cells)-1], VERTICAL, len(cells)}, true
+		}
+		return run{}, false
+	}
+	isEntry := make(map[run]bool)
+	for _, e := range entries {
+		if r, ok := entryRun(e); ok {
+			isEntry[r] = true
+		}
+	}
+	isRun := make(map[run]bool)
+	for _, s := range p.runs() {
+		r := run{s.cells[0], s.dir, len(s.cells)}
+		isRun[r] = true
+		if !isEntry[r] {
+			out = append(out, Violation{VIOLATION_ADJACENCY, s.cells,
+				fmt.Sprintf("%s %s is not an entry", s.pattern(p.grid), directionNames[s.dir])})
+		}
+	}
+	for _, e := range entries {
+		if r, ok := entryRun(e); ok && !isRun[r] {
+			out = append(out, Violation{VIOLATION_ADJACENCY, getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word),
+				fmt.Sprintf("%s runs into neighbouring letters", e.Display)})
+		}
+	}
+
+	listed := make(map[string]bool)
+	for _, w := range words {
+		listed[strings.ToUpper(w)] = true
+	}
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		cells := getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word)
+		if seen[e.Word] {
+			out = append(out, Violation{VIOLATION_DUPLICATE, cells, fmt.Sprintf("%s appears more than once", e.Display)})
+		}
+		seen[e.Word] = true
+		if words != nil && !listed[strings.ToUpper(e.Word)] && !listed[strings.ToUpper(e.Display)] {
+			out = append(out, Violation{VIOLATION_UNLISTED, cells, fmt.Sprintf("%s is not in the word list", e.Display)})
+		}
+	}
+
+	groups := p.components()
+	for _, group := range groups[min(1, len(groups)):] {
+		out = append(out, Violation{VIOLATION_DISCONNECTED, group,
+			fmt.Sprintf("%d letter(s) cut off from the rest of the grid", len(group))})
+	}
+	return out
+}
+
+// components returns the groups of letters connected orthogonally or along a
+// diagonal entry, largest first, each in reading order.
+func (p *Puzzle) components() [][]Pos {
+	links := make(map[Pos][]Pos)
+	for _, e := range p.entries() {
+		cells := getSequence(Pos{e.Row, e.Col}, e.Direction, e.Word)
+		for i := 1; i < len(cells); i++ {
+			links[cells[i-1]] = append(links[cells[i-1]], cells[i])
+			links[cells[i]] = append(links[cells[i]], cells[i-1])
+		}
+	}
+	var groups [][]Pos
+	visited := make(map[Pos]bool)
+	for r := 0; r < p.Rows; r++ {
+		for c := 0; c < p.Cols; c++ {
+			start := Pos{r, c}
+			if visited[start] || p.Cell(r, c) == '#' {
+				continue
+			}
+			visited[start] = true
+			group := []Pos{start}
+			for i := 0; i < len(group); i++ {
+				loc := group[i]
+				neighbours := []Pos{{loc.R - 1, loc.C}, {loc.R + 1, loc.C}, {loc.R, loc.C - 1}, {loc.R, loc.C + 1}}
+				for _, next := range append(neighbours, links[loc]...) {
+					if next.R < 0 || next.R >= p.Rows || next.C < 0 || next.C >= p.Cols || visited[next] || p.Cell(next.R, next.C) == '#' {
+						continue
+					}
+					visited[next] = true
+					group = append(group, next)
+				}
+			}
+			sort.Slice(group, func(i, j int) bool {
+				if group[i].R != group[j].R {
+					return group[i].R < group[j].R
+				}
+				return group[i].C < group[j].C
+			})
+			groups = append(groups, group)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i]) > len(groups[j]) })
+	return groups
+}
@@ -0,0 +1,166 @@
+// file: wasm.go
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+)
+
+// In the browser the binary exposes a global generate(words, options)
+// function instead of running the CLI:
+//
+//	const puzzle = generate(["ALPHA", "BETA"], {size: 10, intersections: 4});
+//
+// options takes the same keys as requirements.toml (size, intersections,
+// iterations, depth) plus density, maxEmpty, minCrossings, allowIslands,
+// mostConstrained, restart, restartUnit, memoMB, directions (a string like
+// the -directions flag), autoSize, crop, margin, rtl, foldAccents, lang (a
+// -lang profile) and the metadata title, author, copyright and notes; missing
+// keys get the CLI's defaults.
+// mode: "wordsearch" returns a word search ({rows, cols, grid, words}) instead.
+// replay: true adds a replay key holding how the grid was built ({size,
+// events}, see Replay), for playing the construction back.
+// The result is the puzzle's JSON form as a JS object, or {error: "..."}.
+// Generation is synchronous, so run it from a Web Worker for large lists.
+func main() {
+	js.Global().Set("generate", js.FuncOf(jsGenerate))
+	select {}
+}
+
+func jsGenerate(this js.Value, args []js.Value) any {
+	if len(args) < 1 || args[0].Type() != js.TypeObject {
+		return jsError("generate(words, options): words must be an array of strings")
+	}
+	words := make([]string, args[0].Length())
+	for i := range words {
+		words[i] = args[0].Index(i).String()
+	}
+
+	options := js.Undefined()
+	if len(args) > 1 {
+		options = args[1]
+	}
+	opts := Options{
+		GridSize:        jsInt(options, "size", DEFAULT_SIZE),
+		MaxIter:         jsInt(options, "iterations", DEFAULT_ITERATIONS),
+		MaxDepth:        jsInt(options, "depth", DEFAULT_DEPTH),
+		Restart:         jsString(options, "restart", "fixed"),
+		RestartUnit:     jsInt(options, "restartUnit", DEFAULT_RESTART_UNIT),
+		MemoMB:          jsInt(options, "memoMB", DEFAULT_MEMO_MB),
+		MinDensity:      jsFloat(options, "density", 0),
+		MaxEmpty:        jsInt(options, "maxEmpty", 0),
+		MinCrossings:    jsInt(options, "minCrossings", 0),
+		AllowIslands:    jsBool(options, "allowIslands", false),
+		MostConstrained: jsBool(options, "mostConstrained", false),
+		FoldAccents:     jsBool(options, "foldAccents", false),
+		Language:        jsString(options, "lang", ""),
+	}
+	opts.ReqIntersections = jsInt(options, "intersections", opts.GridSize-3)
+	if err := checkRestart(opts.Restart); err != nil {
+		return jsError(err.Error())
+	}
+	if _, err := lookupLanguage(opts.Language); err != nil {
+		return jsError(err.Error())
+	}
+	if jsBool(options, "replay", false) {
+		opts.Replay = &Replay{}
+	}
+	wordSearch := options.Type() == js.TypeObject && options.Get("mode").String() == "wordsearch"
+	if dirs := jsString(options, "directions", ""); dirs != "" {
+		var err error
+		if opts.Directions, err = parseDirections(dirs); err != nil {
+			return jsError(err.Error())
+		}
+		if err := checkDirections(opts.Directions); err != nil && !wordSearch {
+			return jsError(err.Error())
+		}
+	}
+
+	// auto-sizing picks the grid size, so only the other settings are checked
+	if !wordSearch && !jsBool(options, "autoSize", false) {
+		if err := opts.Validate(words); err != nil {
+			return jsError(err.Error())
+		}
+	}
+
+	var puzzle json.Marshaler
+	if wordSearch {
+		if ws := generateWordSearch(words, opts); ws != nil {
+			puzzle = ws
+		}
+	} else if jsBool(options, "autoSize", false) {
+		if p := generateAutoSize(words, AUTO_SIZE_MAX, opts); p != nil {
+			puzzle = cropped(p, options)
+		}
+	} else if p := generate(words, opts); p != nil {
+		puzzle = cropped(p, options)
+	}
+	if puzzle == nil {
+		return jsError("no valid puzzle produced")
+	}
+	data, err := json.Marshal(puzzle)
+	if err != nil {
+		return jsError(err.Error())
+	}
+	result := js.Global().Get("JSON").Call("parse", string(data))
+	if opts.Replay != nil && !wordSearch {
+		replay, err := json.Marshal(opts.Replay)
+		if err != nil {
+			return jsError(err.Error())
+		}
+		result.Set("replay", js.Global().Get("JSON").Call("parse", string(replay)))
+	}
+	return result
+}
+
+// cropped sets the metadata options on p and applies the crop, margin and
+// rtl options to it.
+func cropped(p *Puzzle, options js.Value) *Puzzle {
+	p.Meta = Metadata{
+		Title:     jsString(options, "title", ""),
+		Author:    jsString(options, "author", ""),
+		Copyright: jsString(options, "copyright", ""),
+		Notes:     jsString(options, "notes", ""),
+	}
+	if jsBool(options, "crop", false) {
+		p = p.Crop(jsInt(options, "margin", 0))
+	}
+	if jsBool(options, "rtl", false) {
+		p = p.RightToLeft()
+	}
+	return p
+}
+
+func jsError(msg string) any {
+	return map[string]any{"error": msg}
+}
+
+func jsInt(options js.Value, key string, def int) int {
+	if options.Type() != js.TypeObject || options.Get(key).Type() != js.TypeNumber {
+		return def
+	}
+	return options.Get(key).Int()
+}
+
+func jsFloat(options js.Value, key string, def float64) float64 {
+	if options.Type() != js.TypeObject || options.Get(key).Type() != js.TypeNumber {
+		return def
+	}
+	return options.Get(key).Float()
+}
+
+func jsBool(options js.Value, key string, def bool) bool {
+	if options.Type() != js.TypeObject || options.Get(key).Type() != js.TypeBoolean {
+		return def
+	}
+	return options.Get(key).Bool()
+}
+
+func jsString(options js.Value, key string, def string) string {
+	if options.Type() != js.TypeObject || options.Get(key).Type() != js.TypeString {
+		return def
+	}
+	return options.Get(key).String()
+}
@@ -0,0 +1,151 @@
+// file: wordlist.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Downloads of word lists give up after WORDLIST_TIMEOUT and refuse lists
+// over WORDLIST_MAX_BYTES.
+const (
+	WORDLIST_TIMEOUT   = 30 * time.Second
+	WORDLIST_MAX_BYTES = 16 << 20
+)
+
+// readWordFile loads a word list with one word per line. Blank lines and lines
+// starting with '#' are skipped; words are upper-cased to match the grid.
+// The path "-" reads standard input, for lists built in a pipeline:
+//
+//	grep -i '^[a-z]*itis$' /usr/share/dict/words | crossword -wordfile -
+//
+// An http:// or https:// URL is downloaded, for shared lists kept in a gist
+// or a CMS, and BUILTIN_WORDLIST names a list built into the binary. Scores
+// of WORD;score lines are dropped; see readScoredWordFile.
+func readWordFile(path string) ([]string, error) {
+	words, _, err := readScoredWordFile(path)
+	return words, err
+}
+
+// WORD_SCORE_DEFAULT is the score of a word list entry written without one.
+const WORD_SCORE_DEFAULT = 50
+
+// readScoredWordFile is readWordFile for lists in the constructors' WORD;score
+// format (.dict files), where the score rates the word as fill, higher being
+// better. Entries without a score get WORD_SCORE_DEFAULT.
+func readScoredWordFile(path string) ([]string, map[string]int, error) {
+	builtin, isBuiltin, err := builtinWordList(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var r io.Reader
+	switch {
+	case path == "-":
+		r, path = os.Stdin, "stdin"
+	case isBuiltin:
+		r, path = bytes.NewReader(builtin), "built-in word list"
+	case strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://"):
+		data, err := fetchWordList(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = bytes.NewReader(data)
+	default:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var words []string
+	scores := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		score := WORD_SCORE_DEFAULT
+		if word, value, ok := strings.Cut(line, ";"); ok {
+			var err error
+			if score, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: score %q is not a whole number", path, n, value)
+			}
+			line = strings.TrimSpace(word)
+		}
+		word := strings.ToUpper(line)
+		words = append(words, word)
+		scores[word] = score
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(words) == 0 {
+		return nil, nil, fmt.Errorf("%s: no words", path)
+	}
+	return words, scores, nil
+}
+
+// fetchWordList downloads the word list at url.
+func fetchWordList(url string) ([]byte, error) {
+	client := &http.Client{Timeout: WORDLIST_TIMEOUT}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, WORDLIST_MAX_BYTES+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	if len(data) > WORDLIST_MAX_BYTES {
+		return nil, fmt.Errorf("%s: word list larger than %d MB", url, WORDLIST_MAX_BYTES>>20)
+	}
+	return data, nil
+}
+
+// A blocklist is a word list of answers never to use, for puzzles published
+// to children. Entries and answers are compared on their letters alone,
+// case folded, and a phrase is blocked if any of its words is.
+
+// readBlocklist loads the blocklist at path.
+func readBlocklist(path string) (*dictionary, error) {
+	words, err := readWordFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, w := range words {
+		words[i] = clueKey(w)
+	}
+	return newDictionary(words), nil
+}
+
+// onBlocklist reports whether word, or a word of it if it is a phrase, is on
+// block. A nil block lists nothing.
+func onBlocklist(word string, block *dictionary) bool {
+	if block == nil {
+		return false
+	}
+	if block.has(clueKey(word)) {
+		return true
+	}
+	for _, part := range strings.FieldsFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if block.has(clueKey(part)) {
+			return true
+		}
+	}
+	return false
+}
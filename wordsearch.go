@@ -0,0 +1,236 @@
+// file: wordsearch.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// A word search hides the words in a letter grid, in any of the eight
+// directions (or those of Options.Directions) and sharing letters where they agree; the remaining cells are
+// filled with random letters. Placement reuses getSequence and the accent
+// folding of the crossword generator, but there is no adjacency rule, so a
+// greedy random placement with restarts is enough.
+
+// WORDSEARCH_TRIES bounds the random heads/directions tried per word before
+// the grid is restarted.
+const WORDSEARCH_TRIES = 500
+
+// directionNames are the answer-key names of the eight directions.
+var directionNames = [...]string{
+	HORIZONTAL: "right",
+	VERTICAL:   "down",
+	LEFTWARD:   "left",
+	UPWARD:     "up",
+	DOWN_RIGHT: "down-right",
+	UP_LEFT:    "up-left",
+	DOWN_LEFT:  "down-left",
+	UP_RIGHT:   "up-right",
+}
+
+// parseDirections reads a comma-separated list of direction names.
+func parseDirections(s string) ([]int, error) {
+	var dirs []int
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		dir := slices.Index(directionNames[:], name)
+		if dir < 0 {
+			return nil, fmt.Errorf("unknown direction %q (want %s)", name, strings.Join(directionNames[:], ", "))
+		}
+		if slices.Contains(dirs, dir) {
+			return nil, fmt.Errorf("direction %s given twice", name)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// WordSearch is a filled letter grid and the words hidden in it.
+type WordSearch struct {
+	Rows, Cols int
+	grid       map[Pos]rune
+	entries    []Entry // sorted by word; Number is unused
+}
+
+// generateWordSearch hides every word in an opts.GridSize square grid,
+// restarting up to opts.MaxIter times. Words run in opts.Directions, or in
+// all eight directions if it is nil. Returns nil if some word never fit.
+func generateWordSearch(words []string, opts Options) *WordSearch {
+	gridSize := opts.GridSize
+	if gridSize < 1 {
+		return nil
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	// a word hidden twice would have two answers, so duplicates are dropped
+	display := make(map[string]string)
+	var unique []string
+	for _, w := range words {
+		placed := gridForm(w, opts.folds())
+		if _, dup := display[placed]; !dup {
+			display[placed] = w
+			unique = append(unique, placed)
+		}
+	}
+	words = unique
+	dirs := opts.Directions
+	if dirs == nil {
+		dirs = []int{HORIZONTAL, VERTICAL, LEFTWARD, UPWARD, DOWN_RIGHT, UP_LEFT, DOWN_LEFT, UP_RIGHT}
+	}
+	// long words first, while the grid is still empty
+	sort.SliceStable(words, func(i, j int) bool { return len([]rune(words[i])) > len([]rune(words[j])) })
+
+	for iter := 0; iter < opts.MaxIter; iter++ {
+		ws := &WordSearch{Rows: gridSize, Cols: gridSize, grid: make(map[Pos]rune)}
+		ok := true
+		for _, w := range words {
+			if !ws.place(w, display[w], dirs) {
+				logger.Debug("word did not fit, restarting", "iter", iter, "word", w)
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		ws.fill(words, opts.Language)
+		sort.Slice(ws.entries, func(i, j int) bool { return ws.entries[i].Word < ws.entries[j].Word })
+		return ws
+	}
+	return nil
+}
+
+// place puts word at a random head and one of dirs where every cell is empty
+// or already holds the same letter.
+func (ws *WordSearch) place(word, display string, dirs []int) bool {
+	runes := []rune(word)
+	for try := 0; try < WORDSEARCH_TRIES; try++ {
+		head := Pos{rand.Intn(ws.Rows), rand.Intn(ws.Cols)}
+		dir := dirs[rand.Intn(len(dirs))]
+		seq := getSequence(head, dir, word)
+		last := seq[len(seq)-1]
+		if last.R < 0 || last.R >= ws.Rows || last.C < 0 || last.C >= ws.Cols {
+			continue
+		}
+		fits := true
+		for i, loc := range seq {
+			if ch, ok := ws.grid[loc]; ok && ch != runes[i] {
+				fits = false
+				break
+			}
+		}
+		if !fits {
+			continue
+		}
+		for i, loc := range seq {
+			ws.grid[loc] = runes[i]
+		}
+		ws.entries = append(ws.entries, Entry{Row: head.R, Col: head.C, Direction: dir, Word: word, Display: display, Order: len(ws.entries)})
+		return true
+	}
+	return false
+}
+
+// fill puts a random letter in every empty cell. Letters are drawn from the
+// words themselves so the filler matches their alphabet and letter mix, or
+// by the letter frequencies of the language profile if one is chosen.
+func (ws *WordSearch) fill(words []string, language string) {
+	letters := []rune(strings.Join(words, ""))
+	lang, err := lookupLanguage(language)
+	for r := 0; r < ws.Rows; r++ {
+		for c := 0; c < ws.Cols; c++ {
+			if _, ok := ws.grid[Pos{r, c}]; ok {
+				continue
+			}
+			if language != "" && err == nil {
+				ws.grid[Pos{r, c}] = lang.randomLetter()
+			} else {
+				ws.grid[Pos{r, c}] = letters[rand.Intn(len(letters))]
+			}
+		}
+	}
+}
+
+// Cell returns the letter at (r, c).
+func (ws *WordSearch) Cell(r, c int) rune {
+	return ws.grid[Pos{r, c}]
+}
+
+// Entries returns the hidden words in alphabetical order.
+func (ws *WordSearch) Entries() []Entry {
+	return ws.entries
+}
+
+// String returns the letter grid, one row per line.
+func (ws *WordSearch) String() string {
+	var b strings.Builder
+	for r := 0; r < ws.Rows; r++ {
+		for c := 0; c < ws.Cols; c++ {
+			b.WriteRune(ws.Cell(r, c))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// writeWordSearch prints the letter grid, the words to find and, with
+// showKey set, where each word is hidden.
+func writeWordSearch(w io.Writer, ws *WordSearch, showKey bool) {
+	fmt.Fprintln(w, "Word search:")
+	for r := 0; r < ws.Rows; r++ {
+		row := make([]string, ws.Cols)
+		for c := 0; c < ws.Cols; c++ {
+			row[c] = string(ws.Cell(r, c))
+		}
+		fmt.Fprintln(w, strings.Join(row, " "))
+	}
+
+	fmt.Fprintln(w, "\nFind these words:")
+	for _, e := range ws.entries {
+		fmt.Fprintf(w, "  %s\n", e.Display)
+	}
+	if !showKey {
+		return
+	}
+	fmt.Fprintln(w, "\nAnswer key:")
+	for _, e := range ws.entries {
+		fmt.Fprintf(w, "  %s (row %d, col %d, %s)\n", e.Display, e.Row+1, e.Col+1, directionNames[e.Direction])
+	}
+}
+
+type wordSearchJSON struct {
+	Rows  int              `json:"rows"`
+	Cols  int              `json:"cols"`
+	Grid  []string         `json:"grid"`
+	Words []wordSearchWord `json:"words"`
+}
+
+type wordSearchWord struct {
+	Word      string `json:"word"`
+	Display   string `json:"display"`
+	Row       int    `json:"row"`
+	Col       int    `json:"col"`
+	Direction string `json:"direction"`
+}
+
+func (ws *WordSearch) MarshalJSON() ([]byte, error) {
+	out := wordSearchJSON{
+		Rows:  ws.Rows,
+		Cols:  ws.Cols,
+		Grid:  strings.Split(strings.TrimSuffix(ws.String(), "\n"), "\n"),
+		Words: []wordSearchWord{},
+	}
+	for _, e := range ws.entries {
+		out.Words = append(out.Words, wordSearchWord{e.Word, e.Display, e.Row, e.Col, directionNames[e.Direction]})
+	}
+	return json.Marshal(out)
+}
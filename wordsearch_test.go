@@ -0,0 +1,12 @@
+// file: wordsearch_test.go
+package main
+
+import "testing"
+
+func TestGenerateWordSearchEmptyGrid(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		if ws := generateWordSearch([]string{"CAT"}, Options{GridSize: size, MaxIter: 10}); ws != nil {
+			t.Errorf("size %d: got a %dx%d word search", size, ws.Rows, ws.Cols)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// file: xd.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The .xd format is plain text in sections separated by two blank lines:
+// "Key: value" metadata, the grid ('#' for blocks), and the clues as
+// "A1. Clue text ~ ANSWER", across before down. It diffs well, which makes it
+// a good archival form for generated puzzles.
+
+var xdClueRe = regexp.MustCompile(`^([AD])(\d+)\.\s*(.*?)\s*~\s*(\S+)\s*$`)
+
+// writeXD writes p in .xd form.
+func writeXD(w io.Writer, p *Puzzle) error {
+	if err := checkAcrossDown(p, "xd"); err != nil {
+		return err
+	}
+	if p.Barred() {
+		return fmt.Errorf("xd: the format has no bars; export barred grids as ccxml or exolve")
+	}
+	var b strings.Builder
+	meta := false
+	for _, field := range []struct{ key, value string }{
+		{"Title", p.Meta.Title},
+		{"Author", p.Meta.Author},
+		{"Copyright", p.Meta.Copyright},
+		{"Notes", strings.ReplaceAll(p.Meta.Notes, "\n", " ")},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", field.key, field.value)
+			meta = true
+		}
+	}
+	if meta {
+		b.WriteString("\n\n")
+	}
+	b.WriteString(p.String())
+	b.WriteString("\n\n")
+	for i, list := range [][]Entry{p.AcrossEntries(), p.DownEntries()} {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, e := range list {
+			dir := "A"
+			if e.Direction == VERTICAL {
+				dir = "D"
+			}
+			clue := p.ClueText(e)
+			if clue != "" {
+				clue += " "
+			}
+			fmt.Fprintf(&b, "%s%d. %s~ %s\n", dir, e.Number, clue, e.Word)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// readXD parses an .xd file. The metadata section is optional. Lower-case
+// (rebus) cells are read as their upper-case letter and '_' as a block.
+func readXD(r io.Reader) (*Puzzle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	sections := regexp.MustCompile(`\n[ \t]*\n[ \t]*\n+`).Split(strings.Trim(text, "\n"), -1)
+	var meta Metadata
+	if len(sections) > 0 && isXDMetadata(sections[0]) {
+		meta = readXDMetadata(sections[0])
+		sections = sections[1:]
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("xd: no grid")
+	}
+
+	grid := make(map[Pos]rune)
+	rows := strings.Split(strings.TrimSpace(sections[0]), "\n")
+	cols := len([]rune(strings.TrimSpace(rows[0])))
+	for r, line := range rows {
+		line = strings.TrimSpace(line)
+		if len([]rune(line)) != cols {
+			return nil, fmt.Errorf("xd: grid row %d has %d cells, expected %d", r+1, len([]rune(line)), cols)
+		}
+		for c, ch := range line {
+			switch {
+			case ch == '#' || ch == '_':
+			case ch == '.':
+				return nil, fmt.Errorf("xd: grid cell (%d, %d) is not filled in", r+1, c+1)
+			default:
+				grid[Pos{r, c}] = unicode.ToUpper(ch)
+			}
+		}
+	}
+	p := puzzleFromGrid(len(rows), cols, grid)
+	p.Meta = meta
+
+	if len(sections) > 1 {
+		clues := make(map[string]string) // "A1" -> clue
+		for _, line := range strings.Split(sections[1], "\n") {
+			if m := xdClueRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				n, _ := strconv.Atoi(m[2])
+				clues[m[1]+strconv.Itoa(n)] = m[3]
+			}
+		}
+		for _, list := range []struct {
+			dir     string
+			entries []Entry
+		}{{"A", p.across}, {"D", p.down}} {
+			for i := range list.entries {
+				list.entries[i].Clue = clues[list.dir+strconv.Itoa(list.entries[i].Number)]
+			}
+		}
+	}
+	return p, nil
+}
+
+// readXDMetadata picks the fields a Puzzle keeps out of a metadata section.
+func readXDMetadata(section string) Metadata {
+	var m Metadata
+	for _, line := range strings.Split(section, "\n") {
+		key, value, _ := strings.Cut(line, ": ")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "title":
+			m.Title = value
+		case "author":
+			m.Author = value
+		case "copyright":
+			m.Copyright = value
+		case "notes", "note":
+			m.Notes = value
+		}
+	}
+	return m
+}
+
+// isXDMetadata reports whether every line of section is a "Key: value" header.
+func isXDMetadata(section string) bool {
+	for _, line := range strings.Split(section, "\n") {
+		if !strings.Contains(line, ": ") && strings.TrimSpace(line) != "" {
+			return false
+		}
+	}
+	return true
+}
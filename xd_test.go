@@ -0,0 +1,81 @@
+// file: xd_test.go
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testPuzzle returns the puzzle of a grid given as rows of letters and '#'
+// blocks, with bars as for puzzleFromBarredGrid (nil for none) and clues
+// keyed like "A1".
+func testPuzzle(rows []string, bars map[Pos]int, clues map[string]string) *Puzzle {
+	grid := make(map[Pos]rune)
+	for r, row := range rows {
+		for c, ch := range row {
+			if ch != '#' {
+				grid[Pos{r, c}] = ch
+			}
+		}
+	}
+	p := puzzleFromBarredGrid(len(rows), len([]rune(rows[0])), grid, bars)
+	for i := range p.across {
+		p.across[i].Clue = clues["A"+strconv.Itoa(p.across[i].Number)]
+	}
+	for i := range p.down {
+		p.down[i].Clue = clues["D"+strconv.Itoa(p.down[i].Number)]
+	}
+	return p
+}
+
+func TestXDRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		rows  []string
+		meta  Metadata
+		clues map[string]string
+		notes string // Notes read back; the format keeps them on one line
+	}{
+		{"grid only", []string{"CAT", "A#O", "BOP"}, Metadata{}, nil, ""},
+		{"clued", []string{"CAT", "A#O", "BOP"}, Metadata{},
+			map[string]string{"A1": "Pet", "A4": "Light blow", "D1": "Taxi", "D2": "Summit"}, ""},
+		{"metadata", []string{"CAT#", "A#OX", "BOP#"},
+			Metadata{Title: "Mini", Author: "A. Setter", Copyright: "2024", Notes: "Be quick"},
+			map[string]string{"A1": "Pet, often", "D3": "Highest"}, "Be quick"},
+		{"notes over two lines", []string{"GO", "O#"}, Metadata{Notes: "Line one\nline two"}, nil, "Line one line two"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPuzzle(tt.rows, nil, tt.clues)
+			p.Meta = tt.meta
+			var b strings.Builder
+			if err := writeXD(&b, p); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readXD(strings.NewReader(b.String()))
+			if err != nil {
+				t.Fatalf("%v in\n%s", err, b.String())
+			}
+			if got.String() != p.String() {
+				t.Errorf("grid\n%s\nwant\n%s", got, p)
+			}
+			want := tt.meta
+			want.Notes = tt.notes
+			if got.Meta != want {
+				t.Errorf("metadata %+v, want %+v", got.Meta, want)
+			}
+			for i, list := range [][2][]Entry{{got.AcrossEntries(), p.AcrossEntries()}, {got.DownEntries(), p.DownEntries()}} {
+				if len(list[0]) != len(list[1]) {
+					t.Fatalf("direction %d: %d entries, want %d", i, len(list[0]), len(list[1]))
+				}
+				for j, e := range list[0] {
+					w := list[1][j]
+					if e.Number != w.Number || e.Word != w.Word || e.Clue != w.Clue {
+						t.Errorf("entry %d %s %q, want %d %s %q", e.Number, e.Word, e.Clue, w.Number, w.Word, w.Clue)
+					}
+				}
+			}
+		})
+	}
+}
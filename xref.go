@@ -0,0 +1,60 @@
+// file: xref.go
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Clues may point at other answers by writing them in braces, "See {ICE
+// CREAM}". Numbers are only known once the grid is numbered, and change when
+// it is edited or renumbered, so the references stay in Entry.Clue and are
+// resolved whenever a clue is written out, as "12-Across".
+
+var clueRefRe = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// resolveClue replaces the references in clue with the numbers of the
+// answers they name. A reference to an answer that is not in the puzzle is
+// left as the bare answer.
+func (p *Puzzle) resolveClue(clue string) string {
+	if !strings.Contains(clue, "{") {
+		return clue
+	}
+	return clueRefRe.ReplaceAllStringFunc(clue, func(ref string) string {
+		word := ref[1 : len(ref)-1]
+		e, ok := p.Find(strings.TrimSpace(word))
+		if !ok {
+			return word
+		}
+		if isAcross(e.Direction) {
+			return fmt.Sprintf("%d-Across", e.Number)
+		}
+		return fmt.Sprintf("%d-Down", e.Number)
+	})
+}
+
+// ClueText returns the clue of e as printed: references resolved and the
+// enumeration of a phrase appended, "Topping for 3-Down (3,5)". Empty clues
+// stay empty.
+func (p *Puzzle) ClueText(e Entry) string {
+	clue := p.resolveClue(e.Clue)
+	if enum := e.Enumeration(); enum != "" && clue != "" {
+		return clue + " (" + enum + ")"
+	}
+	return clue
+}
+
+// brokenRefs returns the references in the clues of p to answers that are
+// not in the puzzle.
+func (p *Puzzle) brokenRefs() []string {
+	var broken []string
+	for _, e := range p.entries() {
+		for _, m := range clueRefRe.FindAllStringSubmatch(e.Clue, -1) {
+			if _, ok := p.Find(strings.TrimSpace(m[1])); !ok {
+				broken = append(broken, m[1])
+			}
+		}
+	}
+	return broken
+}